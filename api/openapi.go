@@ -0,0 +1,194 @@
+package api
+
+// openAPI describes the API as OpenAPI 3 document
+const openAPI = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "timed",
+    "description": "Working days and sessions of timed. Dates are ISO dates or relative ones like today or yesterday.",
+    "version": "1"
+  },
+  "components": {
+    "securitySchemes": {
+      "token": {"type": "http", "scheme": "bearer", "description": "Only required when a token is configured"}
+    },
+    "parameters": {
+      "date": {"name": "date", "in": "path", "required": true, "schema": {"type": "string"}, "example": "2024-03-28"}
+    },
+    "schemas": {
+      "Session": {
+        "type": "object",
+        "required": ["start"],
+        "properties": {
+          "start": {"type": "string", "format": "date-time"},
+          "end": {"type": "string", "format": "date-time", "nullable": true, "description": "null while running"},
+          "project": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Day": {
+        "type": "object",
+        "properties": {
+          "date": {"type": "string", "format": "date"},
+          "type": {"type": "string", "enum": ["work", "vacation", "sick", "holiday", "comp-time"]},
+          "sessions": {"type": "array", "items": {"$ref": "#/components/schemas/Session"}},
+          "break_minutes": {"type": "integer"},
+          "worked_minutes": {"type": "integer", "readOnly": true},
+          "target_minutes": {"type": "integer", "readOnly": true},
+          "note": {"type": "string"}
+        }
+      },
+      "DayResult": {
+        "type": "object",
+        "properties": {
+          "day": {"$ref": "#/components/schemas/Day"},
+          "warnings": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Clock": {
+        "type": "object",
+        "properties": {
+          "at": {"type": "string", "description": "Time like 08:00 or now-15m (default: now)"},
+          "project": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "note": {"type": "string"},
+          "break_minutes": {"type": "integer"}
+        }
+      },
+      "ClockStatus": {
+        "type": "object",
+        "properties": {
+          "running_since": {"type": "string", "format": "date-time", "nullable": true},
+          "day": {"allOf": [{"$ref": "#/components/schemas/Day"}], "nullable": true}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string"},
+          "problems": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "date": {"type": "string", "format": "date"},
+                "severity": {"type": "string", "enum": ["error", "warning"]},
+                "message": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "responses": {
+      "Invalid": {"description": "Invalid input", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Unauthorized": {"description": "Missing or invalid token", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "NotFound": {"description": "Not found", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Conflict": {"description": "Conflicts with the stored data", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Rejected": {"description": "Impossible working day or unknown project", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Stored": {"description": "Stored working day", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/DayResult"}}}}
+    }
+  },
+  "security": [{"token": []}],
+  "paths": {
+    "/days": {
+      "get": {
+        "summary": "Lists the working days of a range",
+        "parameters": [
+          {"name": "from", "in": "query", "schema": {"type": "string"}, "description": "default: list_days days ago"},
+          {"name": "to", "in": "query", "schema": {"type": "string"}, "description": "default: today"}
+        ],
+        "responses": {
+          "200": {"description": "Working days in chronological order", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Day"}}}}},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/days/{date}": {
+      "parameters": [{"$ref": "#/components/parameters/date"}],
+      "get": {
+        "summary": "Reads a working day",
+        "responses": {
+          "200": {"description": "The working day", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Day"}}}},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      },
+      "put": {
+        "summary": "Creates or replaces a working day",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Day"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Stored"},
+          "201": {"$ref": "#/components/responses/Stored"},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "422": {"$ref": "#/components/responses/Rejected"}
+        }
+      },
+      "delete": {
+        "summary": "Moves a working day into the trash",
+        "responses": {
+          "204": {"description": "Deleted"},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    },
+    "/overtime": {
+      "get": {
+        "summary": "Reads the total overtime",
+        "responses": {
+          "200": {"description": "Overtime", "content": {"application/json": {"schema": {"type": "object", "properties": {"overtime_minutes": {"type": "integer"}}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/clock": {
+      "get": {
+        "summary": "Tells whether a session is running",
+        "responses": {
+          "200": {"description": "Running session", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ClockStatus"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/clock/in": {
+      "post": {
+        "summary": "Starts a running session today",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Clock"}}}},
+        "responses": {
+          "201": {"$ref": "#/components/responses/Stored"},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "422": {"$ref": "#/components/responses/Rejected"}
+        }
+      }
+    },
+    "/clock/out": {
+      "post": {
+        "summary": "Ends the running session",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Clock"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Stored"},
+          "400": {"$ref": "#/components/responses/Invalid"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "422": {"$ref": "#/components/responses/Rejected"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "security": [],
+        "responses": {"200": {"description": "OpenAPI document", "content": {"application/json": {}}}}
+      }
+    }
+  }
+}
+`
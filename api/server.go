@@ -0,0 +1,632 @@
+/*
+Package api serves the working days of timed as REST API with JSON bodies
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+// maxBody limits the size of a request body. A working day is much smaller.
+const maxBody = 1 << 20
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// Server handles the requests of the API. Requests are handled one after another, so the repo is never used
+// concurrently.
+type Server struct {
+	repo  db.Repo
+	token string
+	rules db.Rules
+	mux   *http.ServeMux
+	lock  sync.Mutex
+
+	// listDays is the number of days which are listed without range
+	listDays int
+	// backup saves the database before a working day is deleted
+	backup func() error
+
+	// now returns the current time. Tests replace it.
+	now func() time.Time
+}
+
+// NewServer creates the API on top of the repo. Requests must carry the token as bearer token unless it is empty.
+// Working days which break the rules are stored with warnings, impossible ones are rejected. Without range the last
+// listDays days are listed. The backup is taken before a working day is deleted.
+func NewServer(repo db.Repo, token string, rules db.Rules, listDays int, backup func() error) *Server {
+	s := &Server{repo: repo, token: token, rules: rules, listDays: listDays, mux: http.NewServeMux(), backup: backup, now: time.Now}
+
+	s.mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("/days", s.authorized(s.handleDays))
+	s.mux.HandleFunc("/days/", s.authorized(s.handleDay))
+	s.mux.HandleFunc("/overtime", s.authorized(s.handleOvertime))
+	s.mux.HandleFunc("/clock", s.authorized(s.handleClock))
+	s.mux.HandleFunc("/clock/in", s.authorized(s.handleClockIn))
+	s.mux.HandleFunc("/clock/out", s.authorized(s.handleClockOut))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// DayResult is the response to a change of a working day
+type DayResult struct {
+	Day      db.DayRecord `json:"day"`
+	Warnings []string     `json:"warnings"`
+}
+
+// ClockStatus tells whether a session is running
+type ClockStatus struct {
+	RunningSince *time.Time    `json:"running_since"`
+	Day          *db.DayRecord `json:"day"`
+}
+
+// ClockRequest is the optional body of clocking in and out. At is a time like "08:00" or "now-15m".
+type ClockRequest struct {
+	At           string   `json:"at"`
+	Project      string   `json:"project"`
+	Tags         []string `json:"tags"`
+	Note         string   `json:"note"`
+	BreakMinutes *int     `json:"break_minutes"`
+}
+
+// ErrorResponse is the body of all failed requests. Problems are only set for rejected working days.
+type ErrorResponse struct {
+	Error    string          `json:"error"`
+	Problems []ProblemRecord `json:"problems,omitempty"`
+}
+
+// ProblemRecord is a finding of the validation
+type ProblemRecord struct {
+	Date     string      `json:"date"`
+	Severity db.Severity `json:"severity"`
+	Message  string      `json:"message"`
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// apiError is an error with the status code of the response
+type apiError struct {
+	status   int
+	message  string
+	problems []ProblemRecord
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+// badRequest creates an error for invalid input
+func badRequest(format string, args ...interface{}) error {
+	return apiError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// authorized rejects requests without the token
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			auth := r.Header.Get("Authorization")
+			token := strings.TrimPrefix(auth, "Bearer ")
+			if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="timed"`)
+				s.fail(w, apiError{status: http.StatusUnauthorized, message: "missing or invalid token"})
+				return
+			}
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		next(w, r)
+	}
+}
+
+// allow rejects requests with other methods. It reports whether the request may be handled.
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	s.fail(w, apiError{status: http.StatusMethodNotAllowed, message: fmt.Sprintf("method %s is not allowed", r.Method)})
+	return false
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(openAPI))
+}
+
+// handleDays lists the working days of a range. It defaults to the last listDays days.
+func (s *Server) handleDays(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+
+	now := s.now()
+	from, err := s.queryDate(r, "from", now.AddDate(0, 0, -s.listDays))
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	to, err := s.queryDate(r, "to", now)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if to.Before(from) {
+		s.fail(w, badRequest("to must not be before from"))
+		return
+	}
+
+	days, err := s.repo.ListRange(&from, &to)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	schedule, err := s.repo.Schedule()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	records := make([]db.DayRecord, 0, len(days))
+	for i := len(days) - 1; i >= 0; i-- {
+		records = append(records, days[i].ToRecord(schedule.TargetOfDay(&days[i])))
+	}
+	s.respond(w, http.StatusOK, records)
+}
+
+// handleDay reads, replaces or deletes the working day of the date in the path
+func (s *Server) handleDay(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	value := strings.TrimPrefix(r.URL.Path, "/days/")
+	d, err := when.Date(value, s.now())
+	if err != nil {
+		s.fail(w, badRequest("%v", err))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		wd, err := s.repo.LoadDay(&d)
+		if err != nil {
+			s.fail(w, err)
+			return
+		}
+		s.respondDay(w, http.StatusOK, wd, nil)
+	case http.MethodPut:
+		s.putDay(w, r, d)
+	case http.MethodDelete:
+		wd, err := s.repo.LoadDay(&d)
+		if err != nil {
+			s.fail(w, err)
+			return
+		}
+		if err = s.backup(); err != nil {
+			s.fail(w, err)
+			return
+		}
+		if err = s.repo.Delete(*wd); err != nil {
+			s.fail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// putDay creates or replaces the working day of the date
+func (s *Server) putDay(w http.ResponseWriter, r *http.Request, d time.Time) {
+	var record db.DayRecord
+	if err := decode(w, r, &record, false); err != nil {
+		s.fail(w, err)
+		return
+	}
+	date := d.Format("2006-01-02")
+	if record.Date != "" && record.Date != date {
+		s.fail(w, badRequest("date %s of the body does not match %s", record.Date, date))
+		return
+	}
+	record.Date = date
+
+	wd, err := db.FromRecord(record)
+	if err != nil {
+		s.fail(w, badRequest("%v", err))
+		return
+	}
+	for _, session := range wd.Sessions {
+		if err = s.checkProject(session.Project); err != nil {
+			s.fail(w, err)
+			return
+		}
+	}
+	warnings, err := s.validate(&wd)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if err = s.checkRunning(&wd); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	existing, err := s.repo.LoadDay(&d)
+	if errors.Is(err, db.ErrNotFound) {
+		status = http.StatusCreated
+		err = s.repo.Insert(wd)
+	} else if err == nil {
+		existing.Type, existing.Sessions, existing.Brk, existing.Note = wd.Type, wd.Sessions, wd.Brk, wd.Note
+		err = s.repo.UpdateDay(*existing)
+	}
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	s.respondStored(w, status, d, warnings)
+}
+
+func (s *Server) handleOvertime(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+
+	overtime, err := s.repo.Overtime()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.respond(w, http.StatusOK, map[string]int{"overtime_minutes": overtime})
+}
+
+// handleClock tells whether a session is running
+func (s *Server) handleClock(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+
+	status := ClockStatus{}
+	wd, err := s.repo.LoadRunning()
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		s.fail(w, err)
+		return
+	}
+	if wd != nil {
+		record, err := s.record(wd)
+		if err != nil {
+			s.fail(w, err)
+			return
+		}
+		status.RunningSince, status.Day = &wd.Running().Start, &record
+	}
+	s.respond(w, http.StatusOK, status)
+}
+
+// handleClockIn starts a running session on the current day
+func (s *Server) handleClockIn(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodPost) {
+		return
+	}
+
+	var req ClockRequest
+	if err := decode(w, r, &req, true); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	running, err := s.repo.LoadRunning()
+	if err == nil {
+		s.fail(w, apiError{status: http.StatusConflict,
+			message: fmt.Sprintf("a session is already running since %s", running.Running().Start.Format("2006-01-02 15:04"))})
+		return
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		s.fail(w, err)
+		return
+	}
+	if err = s.checkProject(req.Project); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	now := s.now()
+	start, err := s.clock(req.At, now)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	session := db.Session{Start: start, Project: req.Project, Tags: db.JoinTags(req.Tags)}
+
+	wd, err := s.repo.LoadDay(&now)
+	insert := errors.Is(err, db.ErrNotFound)
+	if insert {
+		wd = &db.WorkingDay{Date: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())}
+	} else if err != nil {
+		s.fail(w, err)
+		return
+	}
+	wd.Sessions = append(wd.Sessions, session)
+	if req.Note != "" {
+		wd.Note = req.Note
+	}
+	if req.BreakMinutes != nil {
+		wd.Brk = *req.BreakMinutes
+	}
+
+	warnings, err := s.validate(wd)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if insert {
+		err = s.repo.Insert(*wd)
+	} else {
+		err = s.repo.UpdateDay(*wd)
+	}
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	s.respondStored(w, http.StatusCreated, now, warnings)
+}
+
+// handleClockOut ends the running session. An end before the start lies on the following day.
+func (s *Server) handleClockOut(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodPost) {
+		return
+	}
+
+	var req ClockRequest
+	if err := decode(w, r, &req, true); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	wd, err := s.repo.LoadRunning()
+	if errors.Is(err, db.ErrNotFound) {
+		s.fail(w, apiError{status: http.StatusNotFound, message: "no running session found"})
+		return
+	}
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if err = s.checkProject(req.Project); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	session := wd.Running()
+	end, err := s.clock(req.At, session.Start)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	end = when.EndAfter(session.Start, end)
+
+	session.End = &end
+	if req.Project != "" {
+		session.Project = req.Project
+	}
+	if len(req.Tags) > 0 {
+		session.Tags = db.JoinTags(req.Tags)
+	}
+	if req.BreakMinutes != nil {
+		wd.Brk = *req.BreakMinutes
+	}
+	if req.Note != "" {
+		wd.Note = req.Note
+	}
+
+	warnings, err := s.validate(wd)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if err = s.repo.UpdateDay(*wd); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	s.respondStored(w, http.StatusOK, wd.Date, warnings)
+}
+
+// clock parses the time of a clock request. It defaults to now.
+func (s *Server) clock(at string, day time.Time) (time.Time, error) {
+	now := s.now()
+	if at == "" {
+		return now, nil
+	}
+	t, err := when.Clock(at, day, now)
+	if err != nil {
+		return t, badRequest("%v", err)
+	}
+	return t, nil
+}
+
+// queryDate parses the date of a query parameter or returns the fallback
+func (s *Server) queryDate(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Date(fallback.Year(), fallback.Month(), fallback.Day(), 0, 0, 0, 0, fallback.Location()), nil
+	}
+	d, err := when.Date(value, s.now())
+	if err != nil {
+		return d, badRequest("%s: %v", name, err)
+	}
+	return d, nil
+}
+
+// checkProject ensures that a project exists and is not archived. An empty name means no project.
+func (s *Server) checkProject(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	p, err := s.repo.LoadProject(name)
+	if errors.Is(err, db.ErrNotFound) {
+		return apiError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("unknown project '%s'", name)}
+	}
+	if err != nil {
+		return err
+	}
+	if p.Archived {
+		return apiError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("project '%s' is archived", name)}
+	}
+	return nil
+}
+
+// validate rejects impossible working days and returns the violated rules as warnings
+func (s *Server) validate(wd *db.WorkingDay) ([]string, error) {
+	warnings, err := wd.Check(s.rules)
+	var invalid *db.InvalidError
+	if errors.As(err, &invalid) {
+		problems := make([]ProblemRecord, 0, len(invalid.Problems))
+		for _, p := range invalid.Problems {
+			problems = append(problems, ProblemRecord{Date: p.Date.Format("2006-01-02"), Severity: p.Severity, Message: p.Message})
+		}
+		return nil, apiError{status: http.StatusUnprocessableEntity, message: db.ErrInvalid.Error(), problems: problems}
+	}
+	if warnings == nil {
+		warnings = make([]string, 0)
+	}
+	return warnings, err
+}
+
+// checkRunning refuses a running session of the working day while a session of another day is running
+func (s *Server) checkRunning(wd *db.WorkingDay) error {
+	if wd.Running() == nil {
+		return nil
+	}
+	running, err := s.repo.LoadRunning()
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if running.Date.Format("2006-01-02") != wd.Date.Format("2006-01-02") {
+		return fmt.Errorf("%w: a session is already running since %s", db.ErrConflict, running.Running().Start.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// record converts the working day with its target
+func (s *Server) record(wd *db.WorkingDay) (db.DayRecord, error) {
+	schedule, err := s.repo.Schedule()
+	if err != nil {
+		return db.DayRecord{}, err
+	}
+	return wd.ToRecord(schedule.TargetOfDay(wd)), nil
+}
+
+// respondDay writes the working day. Warnings turn the response into a DayResult.
+func (s *Server) respondDay(w http.ResponseWriter, status int, wd *db.WorkingDay, warnings []string) {
+	record, err := s.record(wd)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if warnings == nil {
+		s.respond(w, status, record)
+		return
+	}
+	s.respond(w, status, DayResult{Day: record, Warnings: warnings})
+}
+
+// respondStored writes the working day of the date as it was stored
+func (s *Server) respondStored(w http.ResponseWriter, status int, d time.Time, warnings []string) {
+	wd, err := s.repo.LoadDay(&d)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.respondDay(w, status, wd, warnings)
+}
+
+func (s *Server) respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		jww.ERROR.Println(err)
+	}
+}
+
+// fail writes the error with the matching status code. Unexpected errors are logged but not exposed.
+func (s *Server) fail(w http.ResponseWriter, err error) {
+	var e apiError
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(err, db.ErrNotFound):
+		e = apiError{status: http.StatusNotFound, message: "no working day found"}
+	case errors.Is(err, db.ErrConflict):
+		e = apiError{status: http.StatusConflict, message: err.Error()}
+	default:
+		jww.ERROR.Println(err)
+		e = apiError{status: http.StatusInternalServerError, message: "internal error"}
+	}
+	s.respond(w, e.status, ErrorResponse{Error: e.message, Problems: e.problems})
+}
+
+// decode reads the JSON body up to maxBody bytes. An empty body is only accepted when it is optional.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}, optional bool) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if errors.Is(err, io.EOF) && optional {
+		return nil
+	}
+	if err != nil {
+		return badRequest("invalid body: %v", err)
+	}
+	return nil
+}
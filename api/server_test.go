@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+)
+
+// newTestServer serves a fresh database which lists the last two days by default. The clock stands still on a
+// thursday afternoon.
+func newTestServer(t *testing.T, token string) (*httptest.Server, *db.SqlRepo) {
+	dir, err := ioutil.TempDir("", "timed-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repo, err := db.NewRepo(filepath.Join(dir, "timed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.DefaultTarget = 8 * time.Hour
+
+	rules := db.Rules{MaxWorked: 10 * time.Hour, Breaks: []db.BreakRule{{After: 6 * time.Hour, Minimum: 30 * time.Minute}}}
+	s := NewServer(repo, token, rules, 2, func() error { return nil })
+	s.now = func() time.Time { return time.Date(2024, 3, 28, 17, 0, 0, 0, time.Local) }
+
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server, repo
+}
+
+// call sends the request and decodes the response into v unless it is nil
+func call(t *testing.T, server *httptest.Server, method string, path string, body string, v interface{}) int {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Days(t *testing.T) {
+	server, _ := newTestServer(t, "")
+
+	day := `{"sessions": [{"start": "2024-03-27T08:00:00Z", "end": "2024-03-27T16:30:00Z", "project": ""}], "break_minutes": 30, "note": "api"}`
+	var result DayResult
+	if status := call(t, server, http.MethodPut, "/days/2024-03-27", day, &result); status != http.StatusCreated {
+		t.Fatalf("Expected %d but got %d", http.StatusCreated, status)
+	}
+	if result.Day.WorkedMinutes != 8*60 || result.Day.TargetMinutes != 8*60 || len(result.Warnings) != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	// Replacing a day keeps it and reports violated rules
+	long := `{"sessions": [{"start": "2024-03-27T06:00:00Z", "end": "2024-03-27T18:00:00Z"}], "break_minutes": 30}`
+	if status := call(t, server, http.MethodPut, "/days/yesterday", long, &result); status != http.StatusOK {
+		t.Fatalf("Expected %d but got %d", http.StatusOK, status)
+	}
+	if result.Day.Note != "" || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "more than 10h") {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	var record db.DayRecord
+	if status := call(t, server, http.MethodGet, "/days/2024-03-27", "", &record); status != http.StatusOK || record.WorkedMinutes != 11*60+30 {
+		t.Fatalf("Unexpected day %d: %+v", status, record)
+	}
+
+	call(t, server, http.MethodPut, "/days/2024-03-25", `{"type": "vacation"}`, nil)
+	var records []db.DayRecord
+	if status := call(t, server, http.MethodGet, "/days?from=2024-03-01&to=today", "", &records); status != http.StatusOK {
+		t.Fatalf("Expected %d but got %d", http.StatusOK, status)
+	}
+	if len(records) != 2 || records[0].Date != "2024-03-25" || records[1].Date != "2024-03-27" {
+		t.Fatalf("Expected the days in chronological order but got %+v", records)
+	}
+	if call(t, server, http.MethodGet, "/days", "", &records); len(records) != 1 || records[0].Date != "2024-03-27" {
+		t.Fatalf("Expected the days of the configured range but got %+v", records)
+	}
+
+	var overtime map[string]int
+	call(t, server, http.MethodGet, "/overtime", "", &overtime)
+	if overtime["overtime_minutes"] != 3*60+30 {
+		t.Fatalf("Expected '%d' but got %v", 3*60+30, overtime)
+	}
+
+	if status := call(t, server, http.MethodDelete, "/days/2024-03-27", "", nil); status != http.StatusNoContent {
+		t.Fatalf("Expected %d but got %d", http.StatusNoContent, status)
+	}
+	var e ErrorResponse
+	if status := call(t, server, http.MethodGet, "/days/2024-03-27", "", &e); status != http.StatusNotFound || e.Error == "" {
+		t.Fatalf("Expected %d but got %d: %+v", http.StatusNotFound, status, e)
+	}
+}
+
+func TestServer_Invalid(t *testing.T) {
+	server, _ := newTestServer(t, "")
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/days/someday", "", http.StatusBadRequest},
+		{http.MethodGet, "/days?from=2024-03-28&to=2024-03-01", "", http.StatusBadRequest},
+		{http.MethodPut, "/days/2024-03-27", `{"break": 30}`, http.StatusBadRequest},
+		{http.MethodPut, "/days/2024-03-27", `{"date": "2024-03-26"}`, http.StatusBadRequest},
+		{http.MethodPut, "/days/2024-03-27", `{"type": "party"}`, http.StatusBadRequest},
+		{http.MethodPut, "/days/2024-03-27", `{"sessions": [{"start": "2024-03-27T08:00:00Z", "project": "acme"}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/days", "", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/days/2024-03-27", "", http.StatusNotFound},
+		{http.MethodPost, "/clock/out", "", http.StatusNotFound},
+		{http.MethodPost, "/clock/in", `{"at": "noon"}`, http.StatusBadRequest},
+		{http.MethodPost, "/clock/in", `{"project": "` + strings.Repeat("x", maxBody) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var e ErrorResponse
+		if status := call(t, server, tt.method, tt.path, tt.body, &e); status != tt.status || e.Error == "" {
+			t.Errorf("%s %s: expected %d but got %d: %+v", tt.method, tt.path, tt.status, status, e)
+		}
+	}
+
+	// Impossible days are rejected with the problems
+	var e ErrorResponse
+	day := `{"sessions": [{"start": "2024-03-27T16:00:00Z", "end": "2024-03-27T08:00:00Z"}]}`
+	if status := call(t, server, http.MethodPut, "/days/2024-03-27", day, &e); status != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected %d but got %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(e.Problems) != 1 || e.Problems[0].Severity != db.Invalid || !strings.Contains(e.Problems[0].Message, "ends before") {
+		t.Fatalf("Unexpected problems: %+v", e)
+	}
+}
+
+func TestServer_Clock(t *testing.T) {
+	server, repo := newTestServer(t, "")
+	if err := repo.SaveProject(db.Project{Name: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var status ClockStatus
+	call(t, server, http.MethodGet, "/clock", "", &status)
+	if status.RunningSince != nil || status.Day != nil {
+		t.Fatalf("Expected no running session but got %+v", status)
+	}
+
+	var result DayResult
+	if code := call(t, server, http.MethodPost, "/clock/in", `{"at": "08:00", "project": "acme"}`, &result); code != http.StatusCreated {
+		t.Fatalf("Expected %d but got %d", http.StatusCreated, code)
+	}
+	if len(result.Day.Sessions) != 1 || result.Day.Sessions[0].End != nil || result.Day.Sessions[0].Project != "acme" {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if code := call(t, server, http.MethodPost, "/clock/in", "", nil); code != http.StatusConflict {
+		t.Fatalf("Expected %d but got %d", http.StatusConflict, code)
+	}
+
+	call(t, server, http.MethodGet, "/clock", "", &status)
+	if status.RunningSince == nil || status.RunningSince.Hour() != 8 || status.Day.Date != "2024-03-28" {
+		t.Fatalf("Expected the running session but got %+v", status)
+	}
+
+	// Another day must not get a running session too
+	running := `{"sessions": [{"start": "2024-03-27T08:00:00Z", "end": null}]}`
+	if code := call(t, server, http.MethodPut, "/days/2024-03-27", running, nil); code != http.StatusConflict {
+		t.Fatalf("Expected %d for a second running session but got %d", http.StatusConflict, code)
+	}
+	day := time.Date(2024, 3, 27, 0, 0, 0, 0, time.Local)
+	if _, err := repo.LoadDay(&day); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("Stored the second running session: %v", err)
+	}
+
+	// Without time the session ends now
+	if code := call(t, server, http.MethodPost, "/clock/out", `{"break_minutes": 45}`, &result); code != http.StatusOK {
+		t.Fatalf("Expected %d but got %d", http.StatusOK, code)
+	}
+	if result.Day.WorkedMinutes != 8*60+15 || result.Day.Sessions[0].End.Hour() != 17 || len(result.Warnings) != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+}
+
+func TestServer_Backup(t *testing.T) {
+	_, repo := newTestServer(t, "")
+	start := time.Date(2024, 3, 27, 8, 0, 0, 0, time.Local)
+	end := start.Add(8 * time.Hour)
+	if err := repo.Insert(db.WorkingDay{Date: start, Sessions: []db.Session{{Start: start, End: &end}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A working day is not deleted without backup
+	server := httptest.NewServer(NewServer(repo, "", db.Rules{}, 30, func() error { return errors.New("disk full") }))
+	defer server.Close()
+	if code := call(t, server, http.MethodDelete, "/days/2024-03-27", "", nil); code != http.StatusInternalServerError {
+		t.Fatalf("Expected %d but got %d", http.StatusInternalServerError, code)
+	}
+	if _, err := repo.LoadDay(&start); err != nil {
+		t.Fatalf("Deleted the working day without backup: %v", err)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	server, _ := newTestServer(t, "secret")
+
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/overtime", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected %d but got %d", auth, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+
+	if status := call(t, server, http.MethodGet, "/overtime", "", nil); status != http.StatusOK {
+		t.Fatalf("Expected %d but got %d", http.StatusOK, status)
+	}
+
+	// The description is public
+	resp, err := server.Client().Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc map[string]interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil || resp.StatusCode != http.StatusOK || doc["openapi"] != "3.0.3" {
+		t.Fatalf("Invalid OpenAPI document %d: %v", resp.StatusCode, err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	auditCmdProps = AuditCmdProps{}
+
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Shows the audit trail of working days",
+		Long: `Audit lists every alteration of working days with its time, the user of the operating system
+and the values before and after. The audit trail can not be changed and keeps deleted days.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runAudit(auditCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// AuditCmdProps represents all local properties of the audit command
+type AuditCmdProps struct {
+	date string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// auditRecord is the machine readable form of an audit entry
+type auditRecord struct {
+	Time     time.Time       `json:"time"`
+	User     string          `json:"user"`
+	Action   db.ChangeAction `json:"action"`
+	Date     string          `json:"date"`
+	OldValue string          `json:"old_value"`
+	NewValue string          `json:"new_value"`
+}
+
+// runAudit renders the audit entries of the date or of all dates
+func runAudit(props AuditCmdProps, output io.Writer, repo db.Repo) error {
+	var date *time.Time
+	if props.date != "" {
+		d, err := parseDate(props.date)
+		if err != nil {
+			return err
+		}
+		date = &d
+	}
+
+	entries, err := repo.Audit(date)
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Time", "User", "Action", "Date", "Old value", "New value"})
+
+	records := make([]auditRecord, 0, len(entries))
+	for _, e := range entries {
+		before, err := e.Previous()
+		if err != nil {
+			return err
+		}
+		after, err := e.Next()
+		if err != nil {
+			return err
+		}
+
+		r := auditRecord{
+			Time: e.CreatedAt, User: e.User, Action: e.Action, Date: e.Date.Format("2006-01-02"),
+			OldValue: describeDay(before), NewValue: describeDay(after),
+		}
+		t.AppendRow(table.Row{r.Time.Local().Format("2006-01-02 15:04:05"), r.User, r.Action, e.Date.Format(dateLayout()), r.OldValue, r.NewValue})
+		records = append(records, r)
+	}
+
+	return render(output, t, records)
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVarP(&auditCmdProps.date, "date", "d", "", `Shows only the entries of the date. E.g. 2019-03-28, yesterday or -2d. (default: all dates)`)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestRunAudit(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	props := RootCmdProps{date: "2020-08-13", start: "08:00", end: "16:00"}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	props.start = "07:00"
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := runDelete([]string{"2020-08-13"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+	props.date = "2020-08-14"
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	testOut := strings.Builder{}
+	if err := runAudit(AuditCmdProps{date: "2020-08-13"}, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{"|tester|insert|", "|08:00-16:00|07:00-16:00|", "|tester|delete|2020-08-13|07:00-16:00||"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Audit misses '%s': %s", expected, testOut.String())
+		}
+	}
+	if strings.Contains(out, "2020-08-14") {
+		t.Fatalf("Audit contains other dates: %s", testOut.String())
+	}
+
+	if err := runAudit(AuditCmdProps{date: "2020-13-01"}, &testOut, &repo); err == nil {
+		t.Fatal("Accepted invalid date")
+	}
+}
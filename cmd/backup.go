@@ -0,0 +1,261 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Manages backups of the database",
+		Long: `Backup manages copies of a SQLite database in backup_dir. timed takes a backup on its own when the latest one is
+older than backup_interval_hours and before deleting or replacing working days. Only the newest backup_keep
+backups are kept.`,
+	}
+
+	backupNowCmd = &cobra.Command{
+		Use:   "now",
+		Short: "Takes a backup of the database",
+		Long:  "Now copies the database into backup_dir and verifies the copy. The oldest backups beyond backup_keep are removed.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBackupNow(mustBackupSettings()); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	backupListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists the backups",
+		Long:  "List shows all backups starting with the newest one together with the ID which is needed to restore them.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBackupList(os.Stdout, mustBackupSettings()); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	backupRestoreCmd = &cobra.Command{
+		Use:   "restore ID",
+		Short: "Replaces the database by the backup ID",
+		Long: `Restore verifies the backup ID and replaces the database by it. The current database is backed up first, so
+the restore can be reverted with another restore.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBackupRestore(args[0], mustBackupSettings()); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// backupSettings tell where the SQLite database and its backups are
+type backupSettings struct {
+	db   string
+	dir  string
+	keep int
+	// interval is the age of the latest backup which causes a new one. Zero disables the scheduled backups.
+	interval time.Duration
+}
+
+// backupRecord is the machine readable form of a backup
+type backupRecord struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	Path      string    `json:"path"`
+}
+
+// backupSettingsOf returns the configured backup settings. Only SQLite databases have backups.
+func backupSettingsOf() (backupSettings, error) {
+	path, ok := db.SqlitePath(DbPath())
+	if !ok {
+		return backupSettings{}, errors.New("backups are only supported for SQLite databases")
+	}
+	dir, err := cfg.Backups()
+	if err != nil {
+		return backupSettings{}, err
+	}
+	return backupSettings{db: path, dir: dir, keep: cfg.BackupKeep, interval: cfg.BackupInterval()}, nil
+}
+
+// mustBackupSettings returns the configured backup settings or fails
+func mustBackupSettings() backupSettings {
+	s, err := backupSettingsOf()
+	if err != nil {
+		fail(err)
+	}
+	return s
+}
+
+// runBackupNow takes a backup of the database
+func runBackupNow(s backupSettings) error {
+	b, err := db.CreateBackup(s.db, s.dir, s.keep)
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Saved backup %s to '%s'\n", b.ID, b.Path)
+	return nil
+}
+
+// runBackupList renders all backups starting with the newest one
+func runBackupList(output io.Writer, s backupSettings) error {
+	backups, err := db.Backups(s.dir)
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"ID", "Created at", "Size", "Path"})
+
+	records := make([]backupRecord, 0, len(backups))
+	for _, b := range backups {
+		r := backupRecord{ID: b.ID, CreatedAt: b.CreatedAt, Size: b.Size, Path: b.Path}
+		t.AppendRow(table.Row{r.ID, r.CreatedAt.Local().Format("2006-01-02 15:04"), formatSize(r.Size), r.Path})
+		records = append(records, r)
+	}
+
+	return render(output, t, records)
+}
+
+// runBackupRestore replaces the database by the backup with the id after backing up the current database
+func runBackupRestore(id string, s backupSettings) error {
+	b, err := db.FindBackup(s.dir, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return notFound("there is no backup '%s' - see 'timed backup list'", id)
+	}
+	if err != nil {
+		return err
+	}
+	if err = db.VerifyBackup(b.Path); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(s.db); err == nil {
+		// The backup to restore must survive the rotation
+		current, err := db.CreateBackup(s.db, s.dir, 0)
+		if err != nil {
+			return err
+		}
+		jww.FEEDBACK.Printf("Saved backup %s of the current database\n", current.ID)
+	}
+
+	if err = db.RestoreBackup(*b, s.db); err != nil {
+		return err
+	}
+	jww.FEEDBACK.Printf("Restored backup %s to '%s'\n", b.ID, s.db)
+	return nil
+}
+
+// scheduledBackup takes a backup when the latest one is older than the interval. Nothing happens without the
+// database, with disabled backups or without an interval.
+func scheduledBackup(s backupSettings, now time.Time) (*db.Backup, error) {
+	if s.keep < 1 || s.interval <= 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(s.db); err != nil {
+		return nil, nil
+	}
+
+	backups, err := db.Backups(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) > 0 && now.Sub(backups[0].CreatedAt) < s.interval {
+		return nil, nil
+	}
+	return db.CreateBackup(s.db, s.dir, s.keep)
+}
+
+// protect takes a backup of a SQLite database before a destructive command. It is skipped when backups are disabled.
+func protect() {
+	b, err := backupBeforeChange()
+	if err != nil {
+		fail(err)
+	}
+	if b != nil {
+		jww.FEEDBACK.Printf("Saved backup %s\n", b.ID)
+	}
+}
+
+// backupBeforeChange takes a backup of a SQLite database before it is changed. The backup is nil when backups are
+// disabled or there is no local database.
+func backupBeforeChange() (*db.Backup, error) {
+	s, err := backupSettingsOf()
+	if err != nil || s.keep < 1 {
+		return nil, nil
+	}
+	if _, err = os.Stat(s.db); err != nil {
+		return nil, nil
+	}
+
+	b, err := db.CreateBackup(s.db, s.dir, s.keep)
+	if err != nil {
+		return nil, fmt.Errorf("could not back up the database before changing it - set backup_keep to 0 to go on without: %w", err)
+	}
+	return b, nil
+}
+
+// formatSize formats a number of bytes like 12.3 KiB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupNowCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+)
+
+func TestRunBackup(t *testing.T) {
+	dir := t.TempDir()
+	s := backupSettings{db: filepath.Join(dir, "timed.db"), dir: filepath.Join(dir, "backups"), keep: 3, interval: time.Hour}
+
+	if b, err := scheduledBackup(s, time.Now()); err != nil || b != nil {
+		t.Fatalf("Expected no backup without database but got %v (%v)", b, err)
+	}
+
+	repo, err := db.NewRepo(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, time.Now().Location())
+	if err = repo.Insert(newWorkingDay(start, start.Add(4*time.Hour), 0, "before")); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := scheduledBackup(s, time.Now()); err != nil || b == nil {
+		t.Fatalf("Expected a backup without earlier one but got %v", err)
+	}
+	if b, err := scheduledBackup(s, time.Now().Add(30*time.Minute)); err != nil || b != nil {
+		t.Fatalf("Expected no backup within the interval but got %v (%v)", b, err)
+	}
+	if b, err := scheduledBackup(s, time.Now().Add(2*time.Hour)); err != nil || b == nil {
+		t.Fatalf("Expected a backup after the interval but got %v", err)
+	}
+
+	testOut := strings.Builder{}
+	if err = runBackupList(&testOut, s); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ := db.Backups(s.dir)
+	if len(backups) != 2 || !strings.Contains(testOut.String(), backups[0].ID) {
+		t.Fatalf("Expected two backups but got:\n%s", testOut.String())
+	}
+	id := backups[0].ID
+
+	wd, _ := repo.LoadDay(&start)
+	wd.Note = "after"
+	if err = repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if err = runBackupNow(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = runBackupRestore("19700101-000000", s); exitCode(err) != exitNotFound {
+		t.Fatalf("Expected unknown backup but got %v", err)
+	}
+	if err = runBackupRestore(id, s); err != nil {
+		t.Fatal(err)
+	}
+	if repo, err = db.NewRepo(s.db); err != nil {
+		t.Fatal(err)
+	}
+	if wd, _ = repo.LoadDay(&start); wd == nil || wd.Note != "before" {
+		t.Fatalf("Expected the day of the backup but got %v", wd)
+	}
+
+	// The state before the restore was backed up as well
+	if backups, _ = db.Backups(s.dir); len(backups) != 4 {
+		t.Errorf("Expected four backups but got %d", len(backups))
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for size, expected := range map[int64]string{12: "12 B", 2048: "2.0 KiB", 5 * 1024 * 1024: "5.0 MiB"} {
+		if actual := formatSize(size); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	checkCmdProps = CheckCmdProps{}
+
+	checkCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Checks the working days for impossible or suspicious entries",
+		Long: `Check validates the stored working days. Impossible entries like an end before the start, overlapping sessions
+or a break longer than the time worked are errors. Days with more than max_daily_hours or without the break
+required by break_rules get a warning. Check exits with code 6 when it finds errors.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runCheck(checkCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// CheckCmdProps represents all local properties of the check command
+type CheckCmdProps struct {
+	from string
+	to   string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// checkRecord is the machine readable form of a problem
+type checkRecord struct {
+	Date     string      `json:"date"`
+	Severity db.Severity `json:"severity"`
+	Message  string      `json:"message"`
+}
+
+// runCheck renders the problems of all working days in the range. It fails when impossible entries were found.
+func runCheck(props CheckCmdProps, output io.Writer, repo db.Repo) error {
+	var from time.Time
+	if props.from != "" {
+		d, err := parseDate(props.from)
+		if err != nil {
+			return err
+		}
+		from = d
+	}
+	to := time.Date(9999, 12, 31, 0, 0, 0, 0, time.Now().Location())
+	if props.to != "" {
+		d, err := parseDate(props.to)
+		if err != nil {
+			return err
+		}
+		to = d
+	}
+
+	rules, err := validationRules()
+	if err != nil {
+		return err
+	}
+	workingDays, err := repo.ListRange(&from, &to)
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Date", "Severity", "Problem"})
+
+	records := make([]checkRecord, 0)
+	invalid := 0
+	sort.Slice(workingDays, func(i, j int) bool {
+		return workingDays[i].Date.Before(workingDays[j].Date)
+	})
+	for i := range workingDays {
+		for _, p := range workingDays[i].Validate(rules) {
+			if p.Severity == db.Invalid {
+				invalid++
+			}
+			r := checkRecord{Date: p.Date.Format("2006-01-02"), Severity: p.Severity, Message: p.Message}
+			t.AppendRow(table.Row{p.Date.Format(dateLayout()), r.Severity, r.Message})
+			records = append(records, r)
+		}
+	}
+
+	if err = render(output, t, records); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return rejected("found %d impossible entries in %d working days", invalid, len(workingDays))
+	}
+	return nil
+}
+
+// validationRules returns the rules of the configuration for suspicious working days
+func validationRules() (db.Rules, error) {
+	breaks, err := cfg.Breaks()
+	if err != nil {
+		return db.Rules{}, err
+	}
+
+	rules := db.Rules{MaxWorked: cfg.MaxDaily()}
+	for _, b := range breaks {
+		rules.Breaks = append(rules.Breaks, db.BreakRule{After: b.After, Minimum: b.Minimum})
+	}
+	return rules, nil
+}
+
+// validateDay refuses a working day with impossible data. Violated rules are reported as warnings.
+func validateDay(wd *db.WorkingDay) error {
+	rules, err := validationRules()
+	if err != nil {
+		return err
+	}
+
+	warnings, err := wd.Check(rules)
+	for _, w := range warnings {
+		jww.FEEDBACK.Printf("Warning for %s: %s\n", wd.Date.Format(dateLayout()), w)
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVarP(&checkCmdProps.from, "from", "f", "", `Checks the days from this date on. E.g. 2019-03-28, yesterday or -2d. (default: first day)`)
+	checkCmd.Flags().StringVar(&checkCmdProps.to, "to", "", `Checks the days until this date. E.g. 2019-03-28, yesterday or -2d. (default: last day)`)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunCheck(t *testing.T) {
+
+	repo := newFakeRepo()
+	at := func(day, hour int) time.Time {
+		return time.Date(2020, 8, day, hour, 0, 0, 0, time.Now().Location())
+	}
+	repo.Insert(newWorkingDay(at(10, 8), at(10, 16), 30, "fine"))
+	repo.Insert(newWorkingDay(at(11, 6), at(11, 20), 60, "long"))
+
+	testOut := strings.Builder{}
+	if err := runCheck(CheckCmdProps{}, &testOut, &repo); err != nil {
+		t.Fatalf("Warnings were reported as error: %v", err)
+	}
+	if out := testOut.String(); !strings.Contains(out, "worked 13h which is more than 10h") || strings.Contains(out, "2020-08-10") {
+		t.Fatalf("Unexpected problems: %s", out)
+	}
+
+	// Data of earlier versions may be impossible
+	repo.Insert(newWorkingDay(at(12, 16), at(12, 8), 0, "broken"))
+	withOutput(t, "json")
+	testOut.Reset()
+	err := runCheck(CheckCmdProps{from: "2020-08-12"}, &testOut, &repo)
+	if err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Expected the impossible day to be rejected but got %v", err)
+	}
+
+	var records []checkRecord
+	if err = json.Unmarshal([]byte(testOut.String()), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Date != "2020-08-12" || records[0].Severity != "error" {
+		t.Fatalf("Unexpected records: %+v", records)
+	}
+
+	if err = runCheck(CheckCmdProps{to: "someday"}, &testOut, &repo); err == nil {
+		t.Fatal("Accepted an invalid date")
+	}
+}
+
+func TestValidateOnWrite(t *testing.T) {
+
+	repo := newFakeRepo()
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+
+	// Warnings do not prevent the write
+	props := RootCmdProps{date: "2020-08-13", start: "06:00", end: "19:00", brk: breakOf(0)}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if repo.loadDay(day) == nil {
+		t.Fatal("Suspicious working day was not stored")
+	}
+
+	props.brk = breakOf(900)
+	if err := runRoot(props, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted a break longer than the time worked: %v", err)
+	}
+	late := "20:00"
+	if err := runEdit("2020-08-13", EditCmdProps{session: 1, start: &late}, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted an end before the start: %v", err)
+	}
+	if wd := repo.loadDay(day); wd.Brk != 0 || wd.Start().Hour() != 6 {
+		t.Fatalf("Rejected change was stored: %s", wd)
+	}
+}
+
+func TestNegativeBreakIsRejected(t *testing.T) {
+
+	cmd := &cobra.Command{}
+	brk := cmd.Flags().IntP("break", "b", 0, "")
+	if changedBreak(cmd, brk) != nil {
+		t.Fatal("Break without the flag was not left unchanged")
+	}
+	if err := cmd.Flags().Parse([]string{"--break", "-30"}); err != nil {
+		t.Fatal(err)
+	}
+	given := changedBreak(cmd, brk)
+	if given == nil || *given != -30 {
+		t.Fatalf("Negative break was dropped: %v", given)
+	}
+
+	repo := newFakeRepo()
+	props := RootCmdProps{date: "2020-08-13", start: "08:00", end: "16:00", brk: given}
+	if err := runRoot(props, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted a negative break on insert: %v", err)
+	}
+	props.brk = nil
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	props.brk = given
+	if err := runRoot(props, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted a negative break on update: %v", err)
+	}
+
+	if err := runIn(ClockCmdProps{at: "00:00"}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := runOut(ClockCmdProps{brk: given}, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted a negative break on out: %v", err)
+	}
+	if err := runEdit("2020-08-13", EditCmdProps{brk: given}, &repo); err == nil || exitCode(err) != exitRejected ||
+		!strings.Contains(err.Error(), "break must not be negative") {
+		t.Fatalf("Accepted a negative break on edit: %v", err)
+	}
+}
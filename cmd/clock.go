@@ -0,0 +1,219 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	"github.com/spf13/cobra"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	inCmdProps  = ClockCmdProps{}
+	outCmdProps = ClockCmdProps{brk: new(int)}
+
+	inCmd = &cobra.Command{
+		Use:   "in",
+		Short: "Starts a running session",
+		Long:  "In clocks in by starting a session without an end. Only one session can be running at the same time.",
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+			err := runIn(inCmdProps, repo)
+
+			if err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	outCmd = &cobra.Command{
+		Use:   "out",
+		Short: "Ends the running session",
+		Long:  "Out clocks out by setting the end of the currently running session.",
+		Run: func(cmd *cobra.Command, args []string) {
+			props := outCmdProps
+			props.brk = changedBreak(cmd, outCmdProps.brk)
+
+			repo := openRepo()
+			err := runOut(props, repo)
+
+			if err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ClockCmdProps represents all local properties of the in and out command. A nil break is not changed.
+type ClockCmdProps struct {
+	at string
+
+	brk  *int
+	note string
+
+	project string
+	tags    []string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// runIn opens a new session for today.
+func runIn(props ClockCmdProps, repo db.Repo) error {
+
+	running, err := loadRunning(repo)
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		return conflict("a session is already running since %s", running.Running().Start.Format("2006-01-02 15:04"))
+	}
+
+	if err := checkProject(props.project, repo); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s, err := parseClockTime(props.at, now)
+	if err != nil {
+		return err
+	}
+	session := db.Session{Start: s, Project: props.project, Tags: db.JoinTags(props.tags)}
+
+	wd, err := loadDay(repo, &now)
+	if err != nil {
+		return err
+	}
+	if wd != nil {
+		wd.Sessions = append(wd.Sessions, session)
+		if props.note != "" {
+			wd.Note = props.note
+		}
+
+		if err = validateDay(wd); err != nil {
+			return err
+		}
+		if err = repo.UpdateDay(*wd); err != nil {
+			return err
+		}
+	} else {
+		newWd := db.WorkingDay{
+			Date:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+			Sessions: []db.Session{session},
+			Note:     props.note,
+		}
+
+		if err = validateDay(&newWd); err != nil {
+			return err
+		}
+		if err = repo.Insert(newWd); err != nil {
+			return err
+		}
+	}
+
+	return printReport(os.Stdout, repo)
+}
+
+// runOut ends the running session.
+func runOut(props ClockCmdProps, repo db.Repo) error {
+
+	wd, err := loadRunning(repo)
+	if err != nil {
+		return err
+	}
+	if wd == nil {
+		return notFound("no running session found")
+	}
+
+	if err := checkProject(props.project, repo); err != nil {
+		return err
+	}
+
+	session := wd.Running()
+	e := time.Now()
+	if props.at != "" {
+		if e, err = parseClockTime(props.at, session.Start); err != nil {
+			return err
+		}
+		e = when.EndAfter(session.Start, e)
+	}
+
+	session.End = &e
+	if props.project != "" {
+		session.Project = props.project
+	}
+	if len(props.tags) > 0 {
+		session.Tags = db.JoinTags(props.tags)
+	}
+	if props.brk != nil {
+		wd.Brk = *props.brk
+	}
+	if props.note != "" {
+		wd.Note = props.note
+	}
+
+	if err = validateDay(wd); err != nil {
+		return err
+	}
+	if err = repo.UpdateDay(*wd); err != nil {
+		return err
+	}
+
+	return printReport(os.Stdout, repo)
+}
+
+// parseClockTime parses a time like "08:00" or "now-15m" and places it on the passed day. An empty time results in the day itself.
+func parseClockTime(clock string, day time.Time) (time.Time, error) {
+	if clock == "" {
+		return day, nil
+	}
+	return when.Clock(clock, day, time.Now())
+}
+
+func init() {
+	rootCmd.AddCommand(inCmd)
+	inCmd.Flags().StringVarP(&inCmdProps.at, "start", "s", "", `Takes the start time. E.g. "08:00", "now-15m" or "+1h". (default: now)`)
+	inCmd.Flags().StringVarP(&inCmdProps.note, "note", "n", "", "Takes a note and add it to the day. Default: ''")
+	inCmd.Flags().StringVarP(&inCmdProps.project, "project", "p", "", "Takes the project the session is worked for.")
+	inCmd.Flags().StringSliceVarP(&inCmdProps.tags, "tag", "t", nil, "Takes tags of the session. Can be repeated or separated by comma.")
+
+	rootCmd.AddCommand(outCmd)
+	outCmd.Flags().StringVarP(&outCmdProps.at, "end", "e", "", `Takes the end time. E.g. "17:00", "now-15m" or "+1h". (default: now)`)
+	outCmd.Flags().IntVarP(outCmdProps.brk, "break", "b", 0, "Takes the duration of the break of the day in minutes. (default: unchanged)")
+	outCmd.Flags().StringVarP(&outCmdProps.note, "note", "n", "", "Takes a note and add it to the day. Default: ''")
+	outCmd.Flags().StringVarP(&outCmdProps.project, "project", "p", "", "Takes the project the session was worked for. (default: unchanged)")
+	outCmd.Flags().StringSliceVarP(&outCmdProps.tags, "tag", "t", nil, "Takes tags of the session. (default: unchanged)")
+}
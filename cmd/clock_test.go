@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunInAndOut(t *testing.T) {
+
+	repo := newFakeRepo()
+	now := time.Now()
+
+	// in
+	err := runIn(ClockCmdProps{at: "00:00", note: "Clocked"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd := repo.loadDay(now)
+	if wd == nil || wd.Running() == nil {
+		t.Fatal("In did not start a running session")
+	}
+	if wd.Note != "Clocked" {
+		t.Fatalf("In did not store the note: Got '%s'", wd.Note)
+	}
+	if !strings.Contains(wd.Sessions[0].String(), "running") {
+		t.Fatalf("Running session is not shown as running: Got '%s'", wd.Sessions[0].String())
+	}
+	if wd.Worked() <= 0 {
+		t.Fatal("Running session does not count up to now")
+	}
+
+	// second in is refused
+	err = runIn(ClockCmdProps{}, &repo)
+	if err == nil || exitCode(err) != exitConflict {
+		t.Fatal("Second in was not refused while a session is running")
+	}
+
+	// out
+	err = runOut(ClockCmdProps{brk: breakOf(15)}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd = repo.loadDay(now)
+	if wd.Running() != nil || len(wd.Sessions) != 1 || wd.Brk != 15 || wd.Note != "Clocked" {
+		t.Fatalf("Out did not end the running session correctly: %s", wd)
+	}
+
+	// out without running session
+	err = runOut(ClockCmdProps{}, &repo)
+	if err == nil || err.Error() != "no running session found" {
+		t.Fatal("Out does not announce that no session is running")
+	}
+
+	// in again appends a session
+	err = runIn(ClockCmdProps{}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd = repo.loadDay(now)
+	if len(wd.Sessions) != 2 || wd.Running() == nil {
+		t.Fatalf("In did not append a running session: %s", wd)
+	}
+}
+
+func TestRunOutNightShift(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	err := runIn(ClockCmdProps{at: "23:59"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runOut(ClockCmdProps{at: "00:00"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd := repo.loadDay(time.Now())
+	if len(wd.Sessions) != 1 || !wd.Sessions[0].Overnight() || wd.Worked() != time.Minute {
+		t.Fatalf("Out did not end the session on the following day: %s", wd)
+	}
+}
+
+func TestCreateReportWhileRunning(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	err := runIn(ClockCmdProps{at: "00:00"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, _ := createReport(&repo)
+	if !strings.HasPrefix(report, "⏱  Running since ") || !strings.Contains(report, "💪 Worked today") {
+		t.Fatalf("Report does not show the running session: Got '%s'", report)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/corka149/timed/config"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	configGetCmdProps = ConfigGetCmdProps{}
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manages the configuration",
+		Long: `Config reads and changes the config file of timed. Every setting can be overridden
+by an environment variable with the prefix TIMED_ -> E.g. TIMED_DB_PATH overrides db_path.`,
+	}
+
+	configGetCmd = &cobra.Command{
+		Use:   "get KEY",
+		Short: "Prints the value of KEY",
+		Long: `Get prints the effective value of KEY including environment overrides. The API token and the password of
+the database are hidden unless --reveal is given.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runConfigGet(args[0], configGetCmdProps, cfg, os.Stdout); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	configSetCmd = &cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Stores VALUE for KEY in the config file",
+		Long:  "Set validates VALUE and stores it for KEY in the config file. The file is created when it does not exist.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := ConfigPath()
+			if err != nil {
+				fail(err)
+			}
+
+			if err = runConfigSet(args[0], args[1], path); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	configShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Prints the whole configuration",
+		Long:  "Show prints the path of the config file and all effective settings. Secrets are hidden.",
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := ConfigPath()
+			if err != nil {
+				fail(err)
+			}
+
+			runConfigShow(cfg, path, os.Stdout)
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ConfigGetCmdProps represents all local properties of the config get command
+type ConfigGetCmdProps struct {
+	reveal bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// runConfigGet prints the setting. Secrets are hidden unless they are revealed.
+func runConfigGet(key string, props ConfigGetCmdProps, c config.Config, output io.Writer) error {
+	value, err := c.Redacted(key)
+	if props.reveal {
+		value, err = c.Get(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(output, value)
+	return err
+}
+
+// runConfigSet changes the config file only. Environment overrides are not written to the file.
+func runConfigSet(key string, value string, path string) error {
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if err = c.Set(key, value); err != nil {
+		return err
+	}
+
+	if err = c.Save(path); err != nil {
+		return err
+	}
+
+	value, _ = c.Redacted(key)
+	jww.FEEDBACK.Printf("Set %s to '%s' in %s", key, value, path)
+	return nil
+}
+
+func runConfigShow(c config.Config, path string, output io.Writer) {
+	fmt.Fprintf(output, "# %s\n", path)
+	for _, key := range config.Keys() {
+		value, _ := c.Redacted(key)
+		fmt.Fprintf(output, "%s = %s\n", key, value)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configGetCmd.Flags().BoolVar(&configGetCmdProps.reveal, "reveal", false, "Prints secrets like the API token in clear text.")
+}
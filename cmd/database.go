@@ -0,0 +1,238 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	dbMigrateCmdProps  = DbCmdProps{}
+	dbRollbackCmdProps = DbCmdProps{}
+
+	dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Manages the schema of the database",
+		Long: `Db shows and changes the version of the database schema. Every command migrates the database to the latest
+version on its own, so these commands are only needed to inspect the schema or to go back to an earlier version.
+SQLite databases are copied to a file with the suffix .vN.bak before their version changes.`,
+	}
+
+	dbMigrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Applies pending migrations",
+		Long:  "Migrate applies the pending migrations up to --to in order. By default the database gets the latest version.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dbMigrateCmdProps.hasTarget = cmd.Flags().Changed("to")
+			schema := openSchema()
+
+			if err := runDbMigrate(dbMigrateCmdProps, schema); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	dbStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Lists the migrations of the schema",
+		Long:  "Status lists all migrations with the time they were applied. Pending migrations have no time.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			schema := openSchema()
+
+			if err := runDbStatus(os.Stdout, schema); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	dbRollbackCmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "Reverts applied migrations",
+		Long: `Rollback reverts the applied migrations above --to starting with the newest one. By default only the newest
+migration is reverted. Nothing is reverted when one of the migrations cannot be rolled back.
+Reverting version 1 drops all tables and has to be confirmed unless --yes is given. Databases which cannot be
+copied before, like PostgreSQL, additionally need --force.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dbRollbackCmdProps.hasTarget = cmd.Flags().Changed("to")
+			schema := openSchema()
+
+			if err := runDbRollback(dbRollbackCmdProps, os.Stdin, os.Stdout, schema); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// DbCmdProps represents all local properties of the db migrate and db rollback commands
+type DbCmdProps struct {
+	to        int
+	hasTarget bool
+
+	yes   bool
+	force bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// migrationRecord is the machine readable form of a migration
+type migrationRecord struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	AppliedAt  *time.Time `json:"applied_at"`
+	Reversible bool       `json:"reversible"`
+}
+
+// openSchema opens the schema of the configured database without migrating it
+func openSchema() *db.Schema {
+	schema, err := db.OpenSchema(DbPath())
+	if err != nil {
+		fail(err)
+	}
+	return schema
+}
+
+// runDbMigrate applies the pending migrations up to the target version
+func runDbMigrate(props DbCmdProps, schema *db.Schema) error {
+	target := db.LatestVersion()
+	if props.hasTarget {
+		target = props.to
+	}
+
+	applied, err := schema.Migrate(target)
+	reportBackup(schema)
+	for _, m := range applied {
+		jww.FEEDBACK.Printf("Applied migration %s\n", m)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		version, err := schema.Version()
+		if err != nil {
+			return err
+		}
+		jww.FEEDBACK.Printf("No pending migrations - the database has version %d\n", version)
+	}
+	return nil
+}
+
+// runDbStatus renders all migrations with the time they were applied
+func runDbStatus(output io.Writer, schema *db.Schema) error {
+	status, err := schema.Status()
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Version", "Name", "Applied at", "Reversible"})
+
+	records := make([]migrationRecord, 0, len(status))
+	for _, s := range status {
+		r := migrationRecord{Version: s.Version, Name: s.Name, AppliedAt: s.AppliedAt, Reversible: s.Reversible()}
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04")
+		}
+		t.AppendRow(table.Row{r.Version, r.Name, appliedAt, r.Reversible})
+		records = append(records, r)
+	}
+
+	return render(output, t, records)
+}
+
+// runDbRollback reverts the applied migrations above the target version. The newest one is reverted by default.
+// Dropping the tables has to be confirmed.
+func runDbRollback(props DbCmdProps, input io.Reader, output io.Writer, schema *db.Schema) error {
+	version, err := schema.Version()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return notFound("nothing to roll back")
+	}
+
+	target := version - 1
+	if props.hasTarget {
+		target = props.to
+	}
+
+	pending, err := schema.Rollbacks(target)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if m.Drops() && !props.yes && !confirm(fmt.Sprintf("Rolling back migration %s drops all tables with their data. Continue?", m), input, output) {
+			jww.FEEDBACK.Println("Aborted")
+			return nil
+		}
+	}
+
+	reverted, err := schema.Rollback(target, props.force)
+	reportBackup(schema)
+	for _, m := range reverted {
+		jww.FEEDBACK.Printf("Rolled back migration %s\n", m)
+	}
+	return err
+}
+
+// reportBackup tells where the database was copied to before its version changed
+func reportBackup(schema *db.Schema) {
+	if schema.Backup != "" {
+		jww.FEEDBACK.Printf("Saved a copy of the database to '%s'\n", schema.Backup)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbRollbackCmd)
+
+	dbMigrateCmd.Flags().IntVar(&dbMigrateCmdProps.to, "to", 0, "Version to migrate to. Default is the latest version.")
+	dbRollbackCmd.Flags().IntVar(&dbRollbackCmdProps.to, "to", 0, "Version to go back to. Default is the version before the current one.")
+	dbRollbackCmd.Flags().BoolVarP(&dbRollbackCmdProps.yes, "yes", "y", false, "Drops the tables without asking for confirmation.")
+	dbRollbackCmd.Flags().BoolVar(&dbRollbackCmdProps.force, "force", false, "Drops the tables even when the database cannot be copied before.")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corka149/timed/db"
+)
+
+func TestRunDb(t *testing.T) {
+	schema, err := db.OpenSchema(filepath.Join(t.TempDir(), "timed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = runDbRollback(DbCmdProps{}, nil, ioutil.Discard, schema); exitCode(err) != exitNotFound {
+		t.Fatalf("Expected nothing to roll back but got %v", err)
+	}
+	if err = runDbMigrate(DbCmdProps{to: 4, hasTarget: true}, schema); err != nil {
+		t.Fatal(err)
+	}
+	if err = runDbMigrate(DbCmdProps{}, schema); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := schema.Version(); version != db.LatestVersion() {
+		t.Fatalf("Expected the latest version but got %d", version)
+	}
+
+	if err = runDbRollback(DbCmdProps{}, nil, ioutil.Discard, schema); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := schema.Version(); version != db.LatestVersion()-1 {
+		t.Fatalf("Expected the version before the latest but got %d", version)
+	}
+
+	testOut := strings.Builder{}
+	if err = runDbStatus(&testOut, schema); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(testOut.String(), "create tables") || !strings.Contains(testOut.String(), "pending") {
+		t.Errorf("Expected applied and pending migrations but got:\n%s", testOut.String())
+	}
+
+	if err = runDbRollback(DbCmdProps{to: 0, hasTarget: true}, nil, ioutil.Discard, schema); err == nil {
+		t.Error("Rolled back irreversible migrations")
+	}
+}
+
+func TestRunDbRollbackConfirmsDroppingTables(t *testing.T) {
+	schema, err := db.OpenSchema(filepath.Join(t.TempDir(), "timed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = runDbMigrate(DbCmdProps{to: 1, hasTarget: true}, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	testOut := strings.Builder{}
+	if err = runDbRollback(DbCmdProps{}, strings.NewReader("n\n"), &testOut, schema); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := schema.Version(); version != 1 || !strings.Contains(testOut.String(), "drops all tables") {
+		t.Fatalf("Expected the rollback to be aborted but got version %d and:\n%s", version, testOut.String())
+	}
+
+	if err = runDbRollback(DbCmdProps{yes: true}, nil, ioutil.Discard, schema); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := schema.Version(); version != 0 {
+		t.Fatalf("Expected the tables to be dropped but got version %d", version)
+	}
+}
@@ -24,10 +24,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bufio"
 	"errors"
-	"github.com/corka149/timed/db"
-	"time"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/corka149/timed/db"
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
 )
@@ -37,44 +43,177 @@ import (
 // ===================
 
 var (
+	deleteCmdProps = DeleteCmdProps{}
+
 	deleteCmd = &cobra.Command{
-		Use:   "delete",
+		Use:   "delete [DATE...]",
 		Short: "Delete by the provided DATE",
-		Long:  "Delete remove an working time entry forever. The working day will be determined by the provided DATE.",
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Delete moves the working days of the provided DATEs or of the range --from/--to into the trash.
+They can be brought back with 'timed trash restore'. The days can be narrowed down by --project, --tag and --note.
+The days are shown before and have to be confirmed unless --yes is given.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			repo := db.NewRepo(DbPath())
-			err := runDelete(args[0], repo)
+			repo := openRepo()
+			err := runDelete(args, deleteCmdProps, os.Stdin, os.Stdout, repo, protect)
 
 			if err != nil {
-				jww.ERROR.Fatal(err)
+				fail(err)
 			}
 		},
 	}
 )
 
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// DeleteCmdProps represents all local properties of the delete command
+type DeleteCmdProps struct {
+	from string
+	to   string
+
+	project string
+	tag     string
+	note    string
+
+	yes bool
+}
+
 // ===================
 // ===== PRIVATE =====
 // ===================
 
-// runDelete performs the delete flow
-func runDelete(date string, repo db.Repo) error {
+// runDelete performs the delete flow. The backup is taken only after the deletion was confirmed.
+func runDelete(dates []string, props DeleteCmdProps, input io.Reader, output io.Writer, repo db.Repo, backup func()) error {
 
-	d, err := time.Parse("2006-01-02", date)
+	days, err := selectDays(dates, props, repo)
 	if err != nil {
 		return err
 	}
+	if len(days) == 0 {
+		return notFound("no working day found")
+	}
 
-	wd := repo.LoadDay(&d)
-	if wd == nil {
-		return errors.New("no working day found")
+	schedule, err := repo.Schedule()
+	if err != nil {
+		return err
+	}
+	if err = renderTable(days, schedule, output); err != nil {
+		return err
+	}
+
+	if !props.yes && !confirm(fmt.Sprintf("Delete %d working days?", len(days)), input, output) {
+		jww.FEEDBACK.Println("Aborted")
+		return nil
+	}
+
+	backup()
+	err = repo.Transaction(func(tx db.Repo) error {
+		for _, wd := range days {
+			if err := tx.Delete(wd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	repo.Delete(*wd)
-	jww.FEEDBACK.Printf("Deleted successful '%s'", date)
+	jww.FEEDBACK.Printf("Moved %d working days into the trash", len(days))
 	return nil
 }
 
+// selectDays loads the working days of the dates and of the range which match the filters of the props
+func selectDays(dates []string, props DeleteCmdProps, repo db.Repo) ([]db.WorkingDay, error) {
+	if len(dates) == 0 && props.from == "" {
+		return nil, errors.New("no DATE or --from given")
+	}
+	if props.to != "" && props.from == "" {
+		return nil, errors.New("--to needs --from")
+	}
+
+	var note *regexp.Regexp
+	if props.note != "" {
+		var err error
+		if note, err = regexp.Compile(props.note); err != nil {
+			return nil, fmt.Errorf("invalid note pattern: %w", err)
+		}
+	}
+
+	days := make([]db.WorkingDay, 0, len(dates))
+	for _, date := range dates {
+		d, err := parseDate(date)
+		if err != nil {
+			return nil, err
+		}
+
+		wd, err := loadDay(repo, &d)
+		if err != nil {
+			return nil, err
+		}
+		if wd != nil {
+			days = append(days, *wd)
+		}
+	}
+
+	if props.from != "" {
+		from, err := parseDateOrDefault(props.from)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseDateOrDefault(props.to)
+		if err != nil {
+			return nil, err
+		}
+		if to.Before(*from) {
+			return nil, errors.New("end of range is before its start")
+		}
+
+		inRange, err := repo.ListRange(from, to)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, inRange...)
+	}
+
+	// Days selected by date and by range are deleted once
+	seen := make(map[string]bool)
+	selected := make([]db.WorkingDay, 0, len(days))
+	for _, wd := range filterDays(days, props.project, props.tag) {
+		date := wd.Date.Format("2006-01-02")
+		if seen[date] || (note != nil && !note.MatchString(wd.Note)) {
+			continue
+		}
+		seen[date] = true
+		selected = append(selected, wd)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Date.Before(selected[j].Date)
+	})
+	return selected, nil
+}
+
+// confirm asks the question and reports whether it was answered with yes
+func confirm(question string, input io.Reader, output io.Writer) bool {
+	fmt.Fprintf(output, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().StringVarP(&deleteCmdProps.from, "from", "f", "", `Deletes the days from this date on. E.g. 2019-03-28, yesterday or -2d.`)
+	deleteCmd.Flags().StringVar(&deleteCmdProps.to, "to", "", `Deletes the days until this date. E.g. 2019-03-28, yesterday or -2d. (default: today)`)
+	deleteCmd.Flags().StringVarP(&deleteCmdProps.project, "project", "p", "", "Deletes only days with sessions of the project.")
+	deleteCmd.Flags().StringVarP(&deleteCmdProps.tag, "tag", "t", "", "Deletes only days with sessions having the tag.")
+	deleteCmd.Flags().StringVarP(&deleteCmdProps.note, "note", "n", "", "Deletes only days whose note matches the regular expression.")
+	deleteCmd.Flags().BoolVarP(&deleteCmdProps.yes, "yes", "y", false, "Deletes without asking for confirmation.")
 }
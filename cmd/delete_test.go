@@ -1,41 +1,100 @@
 package cmd
 
 import (
-	"github.com/corka149/timed/db"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestRunDelete(t *testing.T) {
 
-	repo := FakeRepo{make(map[string]db.WorkingDay)}
+	repo := newFakeRepo()
 
 	start := time.Date(2018, 10, 8, 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(2018, 10, 8, 16, 20, 00, 000, time.Now().Location())
-	wd := db.WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
+	wd := newWorkingDay(start, end, 30, "With space")
 
 	repo.Insert(wd)
 
-	err := runDelete("2018-10-08", &repo)
+	err := runDelete([]string{"2018-10-08"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	wdFromDb := repo.LoadDay(&wd.Start)
+	wdFromDb := repo.loadDay(wd.Date)
 
 	if wdFromDb != nil {
 		t.Fatal("runDelete" +
 			" did not delete working day")
 	}
 
-	err = runDelete("2018-10-08", &repo)
+	err = runDelete([]string{"2018-10-08"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup)
 
 	if err == nil || err.Error() != "no working day found" {
 		t.Fatal("Delete cmd does not announce fail of not finding a not existing working day")
 	}
+	if code := exitCode(err); code != exitNotFound {
+		t.Fatalf("Expected exit code %d but got %d", exitNotFound, code)
+	}
 
-	err = runDelete("2018-10-32", &repo)
+	err = runDelete([]string{"2018-10-32"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup)
 	if err == nil {
 		t.Fatal("Expected parse error")
 	}
 }
+
+func TestRunDeleteBulk(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	for day := 10; day <= 14; day++ {
+		start := time.Date(2020, 8, day, 8, 0, 0, 0, time.Now().Location())
+		wd := newWorkingDay(start, start.Add(time.Hour), 0, fmt.Sprintf("note %d", day))
+		if day%2 == 0 {
+			wd.Sessions[0].Project = "acme"
+		}
+		repo.Insert(wd)
+	}
+
+	// Without confirmation nothing is deleted nor backed up
+	backups := 0
+	backup := func() { backups++ }
+	testOut := strings.Builder{}
+	props := DeleteCmdProps{from: "2020-08-10", to: "2020-08-14", project: "acme"}
+	if err := runDelete(nil, props, strings.NewReader("n\n"), &testOut, &repo, backup); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data) != 5 || backups != 0 {
+		t.Fatalf("Deleted days without confirmation or took %d backups", backups)
+	}
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	if !strings.Contains(out, "note10") || !strings.Contains(out, "note14") || strings.Contains(out, "note11") ||
+		!strings.Contains(testOut.String(), "Delete 3 working days? [y/N]") {
+		t.Fatalf("Unexpected preview: %s", testOut.String())
+	}
+
+	// Confirmed deletion of the range filtered by project
+	if err := runDelete(nil, props, strings.NewReader("yes\n"), ioutil.Discard, &repo, backup); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data) != 2 || backups != 1 {
+		t.Fatalf("Expected 2 remaining days and a backup but got %d days and %d backups", len(repo.data), backups)
+	}
+
+	// Several dates filtered by note pattern
+	props = DeleteCmdProps{note: "1$", yes: true}
+	if err := runDelete([]string{"2020-08-11", "2020-08-13", "2020-08-11"}, props, nil, ioutil.Discard, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data) != 1 {
+		t.Fatalf("Expected 1 remaining day but got %d", len(repo.data))
+	}
+
+	for _, props := range []DeleteCmdProps{{}, {to: "2020-08-14"}, {from: "2020-08-14", to: "2020-08-10"}, {from: "2020-08-10", note: "("}} {
+		if err := runDelete(nil, props, nil, ioutil.Discard, &repo, noBackup); err == nil {
+			t.Fatalf("Accepted invalid props %+v", props)
+		}
+	}
+}
@@ -0,0 +1,538 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"gopkg.in/yaml.v2"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	editFlags = EditCmdProps{}
+
+	editCmd = &cobra.Command{
+		Use:   "edit DATE",
+		Short: "Edits the working day of DATE",
+		Long: `Edit changes only the fields of the working day which are given as flags.
+The flags of a session change the latest session or the one selected by --session.
+With --editor the working day, or all days until --to, are opened as YAML in $EDITOR and saved on close.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+			props := editProps(cmd)
+
+			var err error
+			if props.editor {
+				err = runEditEditor(args[0], props.to, repo, protect)
+			} else {
+				err = runEdit(args[0], props, repo)
+			}
+			if err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	// launchEditor opens the file in the editor of the user and waits until it is closed
+	launchEditor = func(path string) error {
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+
+		args := strings.Fields(editor)
+		c := exec.Command(args[0], append(args[1:], path)...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return c.Run()
+	}
+)
+
+// editorHeader explains the file opened by edit --editor
+const editorHeader = `# Edit the working days and save the file to apply the changes. Close it unchanged to abort.
+# Times are "hh:mm" on the date of the day. An end before the start lies on the following day.
+# An empty end marks a running session.
+# Dates can not be changed. Days removed from the file stay unchanged.
+`
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// EditCmdProps represents all local properties of the edit command. Fields which are nil are not changed.
+type EditCmdProps struct {
+	dayType *string
+	brk     *int
+	note    *string
+
+	session int
+	start   *string
+	end     *string
+	project *string
+	tags    *[]string
+
+	editor bool
+	to     string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// editDay is the form of a working day in the editor
+type editDay struct {
+	Date     string        `yaml:"date"`
+	Type     string        `yaml:"type"`
+	Break    int           `yaml:"break"`
+	Note     string        `yaml:"note"`
+	Sessions []editSession `yaml:"sessions"`
+}
+
+// editSession is the form of a session in the editor
+type editSession struct {
+	Start   string   `yaml:"start"`
+	End     string   `yaml:"end"`
+	Project string   `yaml:"project,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+}
+
+// editProps keeps only the flags which were given
+func editProps(cmd *cobra.Command) EditCmdProps {
+	props := EditCmdProps{session: editFlags.session, editor: editFlags.editor, to: editFlags.to}
+
+	flags := cmd.Flags()
+	if flags.Changed("type") {
+		props.dayType = editFlags.dayType
+	}
+	if flags.Changed("break") {
+		props.brk = editFlags.brk
+	}
+	if flags.Changed("note") {
+		props.note = editFlags.note
+	}
+	if flags.Changed("start") {
+		props.start = editFlags.start
+	}
+	if flags.Changed("end") {
+		props.end = editFlags.end
+	}
+	if flags.Changed("project") {
+		props.project = editFlags.project
+	}
+	if flags.Changed("tag") {
+		props.tags = editFlags.tags
+	}
+	return props
+}
+
+// runEdit changes the given fields of the working day of the date
+func runEdit(date string, props EditCmdProps, repo db.Repo) error {
+	d, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+
+	wd, err := loadDay(repo, &d)
+	if err != nil {
+		return err
+	}
+	if wd == nil {
+		return notFound("no working day found")
+	}
+
+	if err = editFields(wd, props, repo); err != nil {
+		return err
+	}
+	if err = validateDay(wd); err != nil {
+		return err
+	}
+	if err = repo.UpdateDay(*wd); err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Updated %s: %s\n", wd.Date.Format(dateLayout()), describeDay(wd))
+	return nil
+}
+
+// editFields applies the given fields of the props to the working day
+func editFields(wd *db.WorkingDay, props EditCmdProps, repo db.Repo) error {
+	changed := false
+
+	if props.dayType != nil {
+		dt, err := db.ParseDayType(*props.dayType)
+		if err != nil {
+			return err
+		}
+		wd.Type, changed = dt, true
+	}
+	if props.brk != nil {
+		wd.Brk, changed = *props.brk, true
+	}
+	if props.note != nil {
+		wd.Note, changed = *props.note, true
+	}
+
+	if props.start != nil || props.end != nil || props.project != nil || props.tags != nil {
+		s, err := selectSession(wd, props.session)
+		if err != nil {
+			return err
+		}
+
+		if props.start != nil {
+			if s.Start, err = parseClockTime(*props.start, wd.Date); err != nil {
+				return err
+			}
+		}
+		if props.end != nil {
+			e, err := parseClockTime(*props.end, wd.Date)
+			if err != nil {
+				return err
+			}
+			e = when.EndAfter(s.Start, e)
+			s.End = &e
+		}
+		if props.project != nil {
+			if err = checkProject(*props.project, repo); err != nil {
+				return err
+			}
+			s.Project = *props.project
+		}
+		if props.tags != nil {
+			s.Tags = db.JoinTags(*props.tags)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return errors.New("nothing to change - see 'timed edit --help'")
+	}
+	return nil
+}
+
+// selectSession returns the session with the 1-based index. 0 selects the latest session.
+func selectSession(wd *db.WorkingDay, index int) (*db.Session, error) {
+	if len(wd.Sessions) == 0 {
+		return nil, errors.New("the working day has no sessions - add one with 'timed --add'")
+	}
+	if index == 0 {
+		return &wd.Sessions[len(wd.Sessions)-1], nil
+	}
+	if index < 0 || index > len(wd.Sessions) {
+		return nil, fmt.Errorf("session %d does not exist - the working day has %d sessions", index, len(wd.Sessions))
+	}
+	return &wd.Sessions[index-1], nil
+}
+
+// runEditEditor opens the working days from the date until to in the editor and stores the changes. The backup is
+// taken before the changes are stored.
+func runEditEditor(date string, to string, repo db.Repo, backup func()) error {
+	days, err := editorDays(date, to, repo)
+	if err != nil {
+		return err
+	}
+
+	forms := make([]editDay, 0, len(days))
+	for i := range days {
+		forms = append(forms, toEditDay(&days[i]))
+	}
+	content, err := yaml.Marshal(forms)
+	if err != nil {
+		return err
+	}
+	content = append([]byte(editorHeader), content...)
+
+	file, err := ioutil.TempFile("", "timed-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	if err = launchEditor(file.Name()); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, content) {
+		jww.FEEDBACK.Println("No changes")
+		return nil
+	}
+
+	var editedForms []editDay
+	if err = yaml.UnmarshalStrict(edited, &editedForms); err != nil {
+		return fmt.Errorf("invalid working days: %w", err)
+	}
+
+	changed, err := applyEdits(days, editedForms, repo)
+	if err != nil {
+		return err
+	}
+	for i := range changed {
+		if err = validateDay(&changed[i]); err != nil {
+			return err
+		}
+	}
+	if err = checkRunning(changed, repo); err != nil {
+		return err
+	}
+
+	backup()
+	err = repo.Transaction(func(tx db.Repo) error {
+		for _, wd := range changed {
+			if err := tx.UpdateDay(wd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Updated %d working days\n", len(changed))
+	return nil
+}
+
+// checkRunning refuses running sessions of the changed working days while a session of another day is running.
+// Only one session may run at a time.
+func checkRunning(changed []db.WorkingDay, repo db.Repo) error {
+	stored, err := loadRunning(repo)
+	if err != nil {
+		return err
+	}
+
+	running := make(map[string]*db.Session)
+	if stored != nil {
+		running[stored.Date.Format("2006-01-02")] = stored.Running()
+	}
+	for i := range changed {
+		date := changed[i].Date.Format("2006-01-02")
+		if s := changed[i].Running(); s != nil {
+			running[date] = s
+		} else {
+			delete(running, date)
+		}
+	}
+
+	if len(running) > 1 && stored != nil {
+		return conflict("a session is already running since %s", stored.Running().Start.Format("2006-01-02 15:04"))
+	}
+	if len(running) > 1 {
+		return conflict("only one session may be running but %d are", len(running))
+	}
+	return nil
+}
+
+// editorDays loads the working day of the date or all working days from the date until to in chronological order
+func editorDays(date string, to string, repo db.Repo) ([]db.WorkingDay, error) {
+	from, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == "" {
+		wd, err := loadDay(repo, &from)
+		if err != nil {
+			return nil, err
+		}
+		if wd == nil {
+			return nil, notFound("no working day found")
+		}
+		return []db.WorkingDay{*wd}, nil
+	}
+
+	end, err := parseDate(to)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(from) {
+		return nil, errors.New("end of range is before its start")
+	}
+
+	days, err := repo.ListRange(&from, &end)
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, notFound("no working day found")
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date.Before(days[j].Date)
+	})
+	return days, nil
+}
+
+// toEditDay converts a working day into its form in the editor
+func toEditDay(wd *db.WorkingDay) editDay {
+	form := editDay{
+		Date:     wd.Date.Format("2006-01-02"),
+		Type:     string(wd.Kind()),
+		Break:    wd.Brk,
+		Note:     wd.Note,
+		Sessions: make([]editSession, 0, len(wd.Sessions)),
+	}
+
+	for _, s := range wd.Sessions {
+		es := editSession{Start: s.Start.Format("15:04"), Project: s.Project, Tags: s.TagList()}
+		if !s.Running() {
+			es.End = s.End.Format("15:04")
+		}
+		form.Sessions = append(form.Sessions, es)
+	}
+	return form
+}
+
+// applyEdits validates the edited forms and applies them to the working days. It returns the changed working days.
+func applyEdits(days []db.WorkingDay, forms []editDay, repo db.Repo) ([]db.WorkingDay, error) {
+	byDate := make(map[string]db.WorkingDay, len(days))
+	for _, wd := range days {
+		byDate[wd.Date.Format("2006-01-02")] = wd
+	}
+
+	seen := make(map[string]bool)
+	changed := make([]db.WorkingDay, 0)
+	for _, form := range forms {
+		original, ok := byDate[form.Date]
+		if !ok {
+			return nil, fmt.Errorf("working day '%s' was not opened for editing - dates can not be changed", form.Date)
+		}
+		if seen[form.Date] {
+			return nil, fmt.Errorf("working day '%s' is contained twice", form.Date)
+		}
+		seen[form.Date] = true
+
+		wd, err := fromEditDay(original, form, repo)
+		if err != nil {
+			return nil, fmt.Errorf("working day '%s': %w", form.Date, err)
+		}
+
+		if !reflect.DeepEqual(toEditDay(&original), toEditDay(&wd)) {
+			changed = append(changed, wd)
+		}
+	}
+	return changed, nil
+}
+
+// fromEditDay applies the form to a copy of the original working day. Sessions keep their identity by position.
+func fromEditDay(original db.WorkingDay, form editDay, repo db.Repo) (db.WorkingDay, error) {
+	wd := original
+	wd.Sessions = make([]db.Session, 0, len(form.Sessions))
+
+	dt := db.Work
+	if form.Type != "" {
+		var err error
+		if dt, err = db.ParseDayType(form.Type); err != nil {
+			return wd, err
+		}
+	}
+	wd.Type, wd.Brk, wd.Note = dt, form.Break, form.Note
+
+	for i, es := range form.Sessions {
+		var s db.Session
+		if i < len(original.Sessions) {
+			s = original.Sessions[i]
+		}
+
+		if es.Start == "" {
+			return wd, fmt.Errorf("session %d has no start", i+1)
+		}
+		start, err := parseClockTime(es.Start, wd.Date)
+		if err != nil {
+			return wd, fmt.Errorf("session %d: %w", i+1, err)
+		}
+		s.Start, s.End = start, nil
+
+		if es.End != "" {
+			end, err := parseClockTime(es.End, wd.Date)
+			if err != nil {
+				return wd, fmt.Errorf("session %d: %w", i+1, err)
+			}
+			end = when.EndAfter(start, end)
+			s.End = &end
+		}
+
+		if es.Project != s.Project {
+			if err = checkProject(es.Project, repo); err != nil {
+				return wd, err
+			}
+		}
+		s.Project, s.Tags = es.Project, db.JoinTags(es.Tags)
+
+		wd.Sessions = append(wd.Sessions, s)
+	}
+
+	sort.SliceStable(wd.Sessions, func(i, j int) bool {
+		return wd.Sessions[i].Start.Before(wd.Sessions[j].Start)
+	})
+	return wd, nil
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editFlags.dayType, editFlags.note = new(string), new(string)
+	editFlags.brk = new(int)
+	editFlags.start, editFlags.end, editFlags.project = new(string), new(string), new(string)
+	editFlags.tags = new([]string)
+
+	editCmd.Flags().StringVar(editFlags.dayType, "type", "", "Changes the type of the day: work, vacation, sick, holiday or comp-time.")
+	editCmd.Flags().IntVarP(editFlags.brk, "break", "b", 0, "Changes the break in minutes.")
+	editCmd.Flags().StringVarP(editFlags.note, "note", "n", "", "Changes the note. An empty note removes it.")
+	editCmd.Flags().IntVar(&editFlags.session, "session", 0, "Selects the session by its position starting with 1. (default: latest session)")
+	editCmd.Flags().StringVarP(editFlags.start, "start", "s", "", `Changes the start of the session. E.g. "08:00" or "now-15m".`)
+	editCmd.Flags().StringVarP(editFlags.end, "end", "e", "", `Changes the end of the session. E.g. "08:00" or "now-15m".`)
+	editCmd.Flags().StringVarP(editFlags.project, "project", "p", "", "Changes the project of the session. An empty project removes it.")
+	editCmd.Flags().StringSliceVarP(editFlags.tags, "tag", "t", nil, "Replaces the tags of the session. Can be repeated or separated by comma.")
+	editCmd.Flags().BoolVar(&editFlags.editor, "editor", false, "Opens the working day as YAML in $EDITOR.")
+	editCmd.Flags().StringVar(&editFlags.to, "to", "", `Opens all working days from DATE until this date in the editor. E.g. 2019-03-28 or +1w.`)
+}
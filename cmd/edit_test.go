@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+)
+
+func TestRunEdit(t *testing.T) {
+
+	repo := newFakeRepo()
+	repo.SaveProject(db.Project{Name: "acme"})
+
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, time.Now().Location())
+	wd := newWorkingDay(start, start.Add(4*time.Hour), 30, "keep")
+	afternoon := start.Add(5 * time.Hour)
+	wd.Sessions = append(wd.Sessions, db.Session{Start: afternoon, End: &afternoon})
+	repo.Insert(wd)
+
+	// Only the given fields change
+	note, brk := "changed", 45
+	if err := runEdit("2020-08-13", EditCmdProps{note: &note}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(start); wd.Note != "changed" || wd.Brk != 30 || len(wd.Sessions) != 2 {
+		t.Fatalf("Unexpected day after editing the note: %s", wd)
+	}
+	if err := runEdit("2020-08-13", EditCmdProps{brk: &brk}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(start); wd.Note != "changed" || wd.Brk != 45 {
+		t.Fatalf("Unexpected day after editing the break: %s", wd)
+	}
+
+	// Sessions are selected by position or the latest one
+	end, project, tags := "17:00", "acme", []string{"call"}
+	if err := runEdit("2020-08-13", EditCmdProps{end: &end, project: &project, tags: &tags}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	first := "07:30"
+	if err := runEdit("2020-08-13", EditCmdProps{session: 1, start: &first}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	wd = *repo.loadDay(start)
+	if wd.Sessions[0].Start.Hour() != 7 || wd.Sessions[0].Project != "" ||
+		wd.Sessions[1].End.Hour() != 17 || wd.Sessions[1].Project != "acme" || !wd.Sessions[1].HasTag("call") {
+		t.Fatalf("Unexpected sessions after editing: %s", &wd)
+	}
+
+	// An empty note is a change as well
+	empty := ""
+	if err := runEdit("2020-08-13", EditCmdProps{note: &empty}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(start); wd.Note != "" {
+		t.Fatalf("Note was not removed: %s", wd)
+	}
+
+	late, unknown, invalid := "18:00", "initech", "holidays"
+	for _, props := range []EditCmdProps{{}, {session: 3, start: &first}, {session: 2, start: &late}, {project: &unknown}, {dayType: &invalid}} {
+		if err := runEdit("2020-08-13", props, &repo); err == nil {
+			t.Fatalf("Accepted invalid props %+v", props)
+		}
+	}
+
+	// An end before the start lies on the following day
+	early := "06:00"
+	if err := runEdit("2020-08-13", EditCmdProps{session: 2, end: &early}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(start); !wd.Sessions[1].Overnight() || wd.End().Day() != 14 || wd.End().Hour() != 6 {
+		t.Fatalf("End was not moved to the following day: %s", wd)
+	}
+	if err := runEdit("2020-08-14", EditCmdProps{note: &note}, &repo); err == nil || exitCode(err) != exitNotFound {
+		t.Fatalf("Expected not found but got %v", err)
+	}
+}
+
+func TestRunEditEditor(t *testing.T) {
+
+	repo := newFakeRepo()
+	for day := 13; day <= 14; day++ {
+		start := time.Date(2020, 8, day, 8, 0, 0, 0, time.Now().Location())
+		repo.Insert(newWorkingDay(start, start.Add(4*time.Hour), 0, "day"))
+	}
+
+	original := launchEditor
+	defer func() { launchEditor = original }()
+
+	edit := func(replace ...string) {
+		launchEditor = func(path string) error {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			edited := string(content)
+			for i := 0; i < len(replace); i += 2 {
+				edited = strings.Replace(edited, replace[i], replace[i+1], 1)
+			}
+			return ioutil.WriteFile(path, []byte(edited), 0600)
+		}
+	}
+
+	// Unchanged file changes nothing
+	backups := 0
+	backup := func() { backups++ }
+	edit()
+	if err := runEditEditor("2020-08-13", "2020-08-14", &repo, backup); err != nil || backups != 0 {
+		t.Fatalf("Expected no backup without changes but got %d backups (%v)", backups, err)
+	}
+
+	// The second day of the range gets a new note and an additional session
+	edit("date: \"2020-08-14\"\n  type: work\n  break: 0\n  note: day", "date: \"2020-08-14\"\n  type: work\n  break: 15\n  note: edited")
+	before := launchEditor
+	launchEditor = func(path string) error {
+		if err := before(path); err != nil {
+			return err
+		}
+		content, _ := ioutil.ReadFile(path)
+		edited := string(content) + "  - start: \"13:00\"\n    end: \"15:00\"\n"
+		return ioutil.WriteFile(path, []byte(edited), 0600)
+	}
+	if err := runEditEditor("2020-08-13", "2020-08-14", &repo, backup); err != nil || backups != 1 {
+		t.Fatalf("Expected a backup before the changes but got %d backups (%v)", backups, err)
+	}
+
+	second := time.Date(2020, 8, 14, 0, 0, 0, 0, time.Now().Location())
+	if wd := repo.loadDay(second); wd.Note != "edited" || wd.Brk != 15 || len(wd.Sessions) != 2 || wd.End().Hour() != 15 {
+		t.Fatalf("Editor changes were not applied: %s", wd)
+	}
+	if wd := repo.loadDay(second.AddDate(0, 0, -1)); wd.Note != "day" {
+		t.Fatalf("Unchanged day was altered: %s", wd)
+	}
+
+	// Invalid changes are refused
+	for _, replace := range [][]string{{"2020-08-13", "2020-08-15"}, {"type: work", "type: party"}, {"start: \"08:00\"", "start: \"8 o'clock\""}, {"note", "comment"}} {
+		edit(replace...)
+		if err := runEditEditor("2020-08-13", "", &repo, noBackup); err == nil {
+			t.Fatalf("Accepted invalid change %v", replace)
+		}
+	}
+
+	// A negative break is rejected by the validation
+	edit("break: 0", "break: -5")
+	if err := runEditEditor("2020-08-13", "", &repo, noBackup); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Expected a negative break to be rejected but got %v", err)
+	}
+
+	// A night shift ends on the following day
+	edit("start: \"08:00\"", "start: \"22:00\"", "end: \"12:00\"", "end: \"06:00\"")
+	if err := runEditEditor("2020-08-13", "", &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(second.AddDate(0, 0, -1)); !wd.Sessions[0].Overnight() || wd.Worked() != 8*time.Hour {
+		t.Fatalf("Night shift was not applied: %s", wd)
+	}
+
+	// Only one session may be running
+	running := newWorkingDay(second.AddDate(0, 0, 1).Add(8*time.Hour), second, 0, "running")
+	running.Sessions[0].End = nil
+	repo.Insert(running)
+	edit("end: \"06:00\"", "end: \"\"")
+	if err := runEditEditor("2020-08-13", "", &repo, noBackup); err == nil || exitCode(err) != exitConflict {
+		t.Fatalf("Accepted a second running session: %v", err)
+	}
+	if wd := repo.loadDay(second.AddDate(0, 0, -1)); wd.Running() != nil {
+		t.Fatalf("Second running session was stored: %s", wd)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/corka149/timed/db"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	exportCmdProps  = ExportCmdProps{}
+	restoreCmdProps = RestoreCmdProps{}
+
+	exportCmd = &cobra.Command{
+		Use:   "export [FILE]",
+		Short: "Exports the whole database into an archive",
+		Long: `Export writes every record including deleted ones and their metadata into a versioned archive.
+The archive is written to FILE or to stdout. It can be read again with restore.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			output := io.Writer(os.Stdout)
+			if len(args) == 1 {
+				file, err := os.Create(args[0])
+				if err != nil {
+					fail(err)
+				}
+				defer file.Close()
+				output = file
+
+				if exportCmdProps.format == "" && filepath.Ext(args[0]) == ".ndjson" {
+					exportCmdProps.format = "ndjson"
+				}
+			}
+
+			if err := runExport(exportCmdProps, output, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	restoreCmd = &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Restores the database from an archive",
+		Long: `Restore rebuilds the database from an archive which was created by export.
+Format version and checksum of the archive are verified before anything is written.
+An existing database is only replaced with --force. It is kept as a copy with the suffix .bak.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := os.Open(args[0])
+			if err != nil {
+				fail(err)
+			}
+			defer file.Close()
+
+			archive, err := db.ReadArchive(file)
+			if err != nil {
+				fail(err)
+			}
+
+			if restoreCmdProps.force {
+				protect()
+			}
+			if path, ok := db.LocalPath(DbPath()); ok {
+				if err = prepareRestore(path, restoreCmdProps.force); err != nil {
+					fail(err)
+				}
+			}
+
+			repo := openRepo()
+			if err = runRestore(archive, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ExportCmdProps represents all properties of the export command
+type ExportCmdProps struct {
+	format string
+}
+
+// RestoreCmdProps represents all properties of the restore command
+type RestoreCmdProps struct {
+	force bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// runExport writes all records of the repo as archive
+func runExport(props ExportCmdProps, output io.Writer, repo db.Repo) error {
+	tables, err := repo.Dump()
+	if err != nil {
+		return err
+	}
+
+	archive, err := db.NewArchive(tables, fmt.Sprintf("timed %d.%d.%d", major, minor, patch))
+	if err != nil {
+		return err
+	}
+
+	switch props.format {
+	case "", "json":
+		return archive.WriteJSON(output)
+	case "ndjson":
+		return archive.WriteNDJSON(output)
+	default:
+		return fmt.Errorf("unknown archive format '%s' - expected json or ndjson", props.format)
+	}
+}
+
+// prepareRestore makes sure that the database at path can be rebuilt. An existing database is moved aside when forced.
+func prepareRestore(path string, force bool) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	if !force {
+		return fmt.Errorf("database '%s' already exists - use --force to replace it", path)
+	}
+
+	backup := path + ".bak"
+	if err = os.Rename(path, backup); err != nil {
+		return err
+	}
+	jww.FEEDBACK.Printf("Moved existing database to '%s'\n", backup)
+	return nil
+}
+
+// runRestore inserts all records of the archive into the empty repo
+func runRestore(archive *db.Archive, repo db.Repo) error {
+	if err := archive.Verify(); err != nil {
+		return err
+	}
+
+	err := repo.Restore(archive.Tables)
+	if errors.Is(err, db.ErrNotEmpty) {
+		return errors.New("database is not empty - restore needs a fresh database")
+	}
+	if err != nil {
+		return err
+	}
+
+	t := archive.Tables
+	jww.FEEDBACK.Printf("Restored %d working days, %d sessions, %d targets and %d projects from %s\n",
+		len(t.WorkingDays), len(t.Sessions), len(t.Targets), len(t.Projects), archive.CreatedAt.Format("2006-01-02 15:04"))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	exportCmd.Flags().StringVarP(&exportCmdProps.format, "format", "f", "", "Format of the archive: json or ndjson. (default: json or by extension of FILE)")
+	restoreCmd.Flags().BoolVar(&restoreCmdProps.force, "force", false, "Replaces an existing database. A copy is kept with the suffix .bak.")
+}
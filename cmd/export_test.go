@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+)
+
+func TestRunExportAndRestore(t *testing.T) {
+
+	repo := newFakeRepo()
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, time.Now().Location())
+	repo.Insert(newWorkingDay(start, start.Add(4*time.Hour), 30, "export"))
+	repo.SaveProject(db.Project{Name: "acme"})
+
+	for _, format := range []string{"json", "ndjson"} {
+		testOut := strings.Builder{}
+		if err := runExport(ExportCmdProps{format: format}, &testOut, &repo); err != nil {
+			t.Fatal(err)
+		}
+
+		archive, err := db.ReadArchive(strings.NewReader(testOut.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored := newFakeRepo()
+		if err = runRestore(archive, &restored); err != nil {
+			t.Fatal(err)
+		}
+		wd := restored.loadDay(start)
+		if wd == nil || wd.Note != "export" || wd.Worked() != 3*time.Hour+30*time.Minute || restored.loadProject("acme") == nil {
+			t.Fatalf("%s: restored unexpected day %v", format, wd)
+		}
+
+		if err = runRestore(archive, &restored); err == nil {
+			t.Fatalf("%s: restored into a database with records", format)
+		}
+	}
+
+	if err := runExport(ExportCmdProps{format: "xml"}, ioutil.Discard, &repo); err == nil {
+		t.Fatal("Accepted unknown format")
+	}
+}
+
+func TestPrepareRestore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "timed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "timed.db")
+	if err = prepareRestore(path, false); err != nil {
+		t.Fatalf("Refused missing database: %s", err)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = prepareRestore(path, false); err == nil {
+		t.Fatal("Replaced existing database without --force")
+	}
+	if err = prepareRestore(path, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path + ".bak"); err != nil {
+		t.Fatal("Existing database was not kept")
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	historyCmdProps = HistoryCmdProps{}
+
+	undoCmd = &cobra.Command{
+		Use:   "undo",
+		Short: "Reverts the latest change",
+		Long:  "Undo restores the state of the working day before its latest change. It can be repeated to go further back.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runUndo(repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	redoCmd = &cobra.Command{
+		Use:   "redo",
+		Short: "Applies the latest undone change again",
+		Long:  "Redo applies changes which were reverted by undo again. Undone changes are discarded by any new change.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runRedo(repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Lists the recent changes",
+		Long:  "History shows the recent changes of working days with their state before and after the change.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runHistory(historyCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// HistoryCmdProps represents all local properties of the history command
+type HistoryCmdProps struct {
+	limit int
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// historyRecord is the machine readable form of a change
+type historyRecord struct {
+	ID     uint            `json:"id"`
+	Time   time.Time       `json:"time"`
+	Action db.ChangeAction `json:"action"`
+	Date   string          `json:"date"`
+	Before string          `json:"before"`
+	After  string          `json:"after"`
+	Undone bool            `json:"undone"`
+}
+
+// runUndo reverts the latest change
+func runUndo(repo db.Repo) error {
+	c, err := repo.Undo()
+	if errors.Is(err, db.ErrNotFound) {
+		return notFound("nothing to undo")
+	}
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Undid %s of %s\n", c.Action, c.Date.Format(dateLayout()))
+	return nil
+}
+
+// runRedo applies the latest undone change again
+func runRedo(repo db.Repo) error {
+	c, err := repo.Redo()
+	if errors.Is(err, db.ErrNotFound) {
+		return notFound("nothing to redo")
+	}
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Redid %s of %s\n", c.Action, c.Date.Format(dateLayout()))
+	return nil
+}
+
+// runHistory renders the recent changes starting with the newest one
+func runHistory(props HistoryCmdProps, output io.Writer, repo db.Repo) error {
+	if props.limit < 1 {
+		return errors.New("limit must be at least 1")
+	}
+
+	changes, err := repo.History(props.limit)
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"#", "Time", "Action", "Date", "Before", "After", "Undone"})
+
+	records := make([]historyRecord, 0, len(changes))
+	for _, c := range changes {
+		before, err := c.Previous()
+		if err != nil {
+			return err
+		}
+		after, err := c.Next()
+		if err != nil {
+			return err
+		}
+
+		r := historyRecord{
+			ID: c.ID, Time: c.CreatedAt, Action: c.Action, Date: c.Date.Format("2006-01-02"),
+			Before: describeDay(before), After: describeDay(after), Undone: c.Undone,
+		}
+		t.AppendRow(table.Row{r.ID, r.Time.Local().Format("2006-01-02 15:04"), r.Action, c.Date.Format(dateLayout()), r.Before, r.After, r.Undone})
+		records = append(records, r)
+	}
+
+	return render(output, t, records)
+}
+
+// describeDay summarizes a working day in one line. It is empty for a day which does not exist.
+func describeDay(wd *db.WorkingDay) string {
+	if wd == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, len(wd.Sessions)+3)
+	if wd.Kind() != db.Work {
+		parts = append(parts, string(wd.Kind()))
+	}
+	for _, s := range wd.Sessions {
+		parts = append(parts, s.String())
+	}
+	if wd.Brk > 0 {
+		parts = append(parts, fmt.Sprintf("break %dm", wd.Brk))
+	}
+	if wd.Note != "" {
+		parts = append(parts, fmt.Sprintf("note '%s'", wd.Note))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(redoCmd)
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().IntVarP(&historyCmdProps.limit, "limit", "l", 20, "Number of changes to show.")
+}
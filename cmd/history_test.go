@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunUndoRedoHistory(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	if err := runUndo(&repo); err == nil || exitCode(err) != exitNotFound {
+		t.Fatalf("Expected nothing to undo but got %v", err)
+	}
+
+	props := RootCmdProps{date: "2020-08-13", start: "08:00", end: "16:00", brk: breakOf(30), note: "day"}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	props.start = "09:00"
+	props.end = ""
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+	if err := runUndo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(day); wd.Start().Hour() != 8 {
+		t.Fatalf("Undo did not restore the start: %s", wd)
+	}
+
+	testOut := strings.Builder{}
+	if err := runHistory(HistoryCmdProps{limit: 10}, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	if !strings.Contains(out, "|update|") || !strings.Contains(out, "09:00") || !strings.Contains(out, "|true|") {
+		t.Fatalf("History misses the undone update: %s", testOut.String())
+	}
+
+	if err := runRedo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(day); wd.Start().Hour() != 9 {
+		t.Fatalf("Redo did not apply the start again: %s", wd)
+	}
+	if err := runRedo(&repo); err == nil {
+		t.Fatal("Redo without undone change was accepted")
+	}
+
+	if err := runHistory(HistoryCmdProps{}, &testOut, &repo); err == nil {
+		t.Fatal("Accepted limit of 0")
+	}
+}
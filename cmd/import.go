@@ -0,0 +1,449 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictMerge     = "merge"
+)
+
+var (
+	importCmdProps = ImportCmdProps{}
+
+	// importFields are the CSV columns which are read by the import
+	importFields = []string{"date", "type", "start", "end", "break_minutes", "project", "tags", "note"}
+	// timeOfDay matches the absolute times like "08:00" which are imported. Relative times like "now" would depend
+	// on the time of the import.
+	timeOfDay = regexp.MustCompile(`^\s*\d{1,2}:\d{2}\s*$`)
+
+	importCmd = &cobra.Command{
+		Use:   "import FILE",
+		Short: "Imports working days from a CSV or JSON file",
+		Long: `Import reads working days from FILE in the same schema as "timed list -o csv" or "timed list -o json".
+CSV columns with other names can be mapped -> E.g. --map date=Datum --map start=Beginn.
+Start and end can be ISO-8601 timestamps or times like "08:00". Rows of the same date become one day.
+Days which already exist are handled by the conflict policy: skip, overwrite or merge (adds missing sessions).
+Nothing is imported when any day is invalid.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runImport(args[0], importCmdProps, os.Stdout, repo, protect); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ImportCmdProps represents all local properties of the import command
+type ImportCmdProps struct {
+	format    string
+	delimiter string
+	mapping   []string
+
+	conflict string
+	dryRun   bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// importAction is the planned change for one imported day
+type importAction struct {
+	action string
+	day    db.WorkingDay
+}
+
+// runImport reads and stores the days. The backup is taken only when existing days are changed.
+func runImport(path string, props ImportCmdProps, output io.Writer, repo db.Repo, backup func()) error {
+	if props.conflict != conflictSkip && props.conflict != conflictOverwrite && props.conflict != conflictMerge {
+		return fmt.Errorf("unknown conflict policy '%s' - use skip, overwrite or merge", props.conflict)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	f := props.format
+	if f == "" {
+		f = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	var days []db.WorkingDay
+	switch f {
+	case "json":
+		days, err = readJSONDays(file)
+	case "csv":
+		days, err = readCSVDays(file, props)
+	default:
+		return fmt.Errorf("unknown import format '%s' - use csv or json", f)
+	}
+	if err != nil {
+		return err
+	}
+
+	actions, err := planImport(days, props.conflict, repo)
+	if err != nil {
+		return err
+	}
+	for i := range actions {
+		if actions[i].action == conflictSkip {
+			continue
+		}
+		if err = validateDay(&actions[i].day); err != nil {
+			return err
+		}
+	}
+
+	if props.dryRun {
+		return renderImport(actions, output)
+	}
+	for _, a := range actions {
+		if a.action == conflictOverwrite || a.action == conflictMerge {
+			backup()
+			break
+		}
+	}
+
+	err = repo.Transaction(func(tx db.Repo) error {
+		for _, a := range actions {
+			for _, s := range a.day.Sessions {
+				if s.Project == "" {
+					continue
+				}
+				_, err := tx.LoadProject(s.Project)
+				if errors.Is(err, db.ErrNotFound) {
+					if err = tx.SaveProject(db.Project{Name: s.Project}); err != nil {
+						return err
+					}
+					jww.FEEDBACK.Printf("Added project '%s'\n", s.Project)
+				} else if err != nil {
+					return err
+				}
+			}
+
+			var err error
+			switch a.action {
+			case "insert":
+				err = tx.Insert(a.day)
+			case conflictOverwrite, conflictMerge:
+				err = tx.UpdateDay(a.day)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+	for _, a := range actions {
+		if a.action != conflictSkip {
+			imported++
+		}
+	}
+	jww.FEEDBACK.Printf("Imported %d days (skipped %d existing days)", imported, len(actions)-imported)
+	return nil
+}
+
+// planImport decides for every imported day how it is stored
+func planImport(days []db.WorkingDay, conflict string, repo db.Repo) ([]importAction, error) {
+	actions := make([]importAction, 0, len(days))
+
+	for _, day := range days {
+		existing, err := loadDay(repo, &day.Date)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			actions = append(actions, importAction{action: "insert", day: day})
+			continue
+		}
+
+		switch conflict {
+		case conflictOverwrite:
+			day.Model = existing.Model
+			actions = append(actions, importAction{action: conflictOverwrite, day: day})
+		case conflictMerge:
+			actions = append(actions, importAction{action: conflictMerge, day: mergeDays(*existing, day)})
+		default:
+			actions = append(actions, importAction{action: conflictSkip, day: *existing})
+		}
+	}
+
+	return actions, nil
+}
+
+// mergeDays adds the sessions of the imported day which do not exist yet. Break and type of the existing day win.
+func mergeDays(existing db.WorkingDay, imported db.WorkingDay) db.WorkingDay {
+	for _, s := range imported.Sessions {
+		found := false
+		for _, e := range existing.Sessions {
+			if e.Start.Equal(s.Start) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing.Sessions = append(existing.Sessions, s)
+		}
+	}
+	sort.SliceStable(existing.Sessions, func(i, j int) bool {
+		return existing.Sessions[i].Start.Before(existing.Sessions[j].Start)
+	})
+
+	if existing.Brk == 0 {
+		existing.Brk = imported.Brk
+	}
+	if imported.Note != "" && imported.Note != existing.Note {
+		if existing.Note == "" {
+			existing.Note = imported.Note
+		} else {
+			existing.Note += "; " + imported.Note
+		}
+	}
+
+	return existing
+}
+
+func renderImport(actions []importAction, output io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Date", "Action", "Type", "Sessions", "Break", "Note"})
+
+	type previewRecord struct {
+		Action string       `json:"action"`
+		Day    db.DayRecord `json:"day"`
+	}
+	records := make([]previewRecord, 0, len(actions))
+
+	for _, a := range actions {
+		sessions := make([]string, 0, len(a.day.Sessions))
+		for _, s := range a.day.Sessions {
+			sessions = append(sessions, s.String())
+		}
+		t.AppendRow(table.Row{a.day.Date.Format(dateLayout()), a.action, a.day.Kind(), strings.Join(sessions, ", "), a.day.Brk, a.day.Note})
+		records = append(records, previewRecord{Action: a.action, Day: a.day.ToRecord(0)})
+	}
+
+	return render(output, t, records)
+}
+
+// readJSONDays reads working days in the schema of the JSON output of list
+func readJSONDays(input io.Reader) ([]db.WorkingDay, error) {
+	var records []db.DayRecord
+	if err := json.NewDecoder(input).Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	days := make([]db.WorkingDay, 0, len(records))
+	for i, r := range records {
+		wd, err := db.FromRecord(r)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		days = append(days, wd)
+	}
+
+	return combineDays(days), nil
+}
+
+// readCSVDays reads working days in the schema of the CSV output of list. Columns can be mapped to other names.
+func readCSVDays(input io.Reader, props ImportCmdProps) ([]db.WorkingDay, error) {
+	columns := make(map[string]string, len(importFields))
+	for _, f := range importFields {
+		columns[f] = f
+	}
+	for _, m := range props.mapping {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || columns[parts[0]] == "" {
+			return nil, fmt.Errorf("invalid mapping '%s' - use field=column with a field of %s", m, strings.Join(importFields, ", "))
+		}
+		columns[parts[0]] = parts[1]
+	}
+
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	if props.delimiter != "" {
+		reader.Comma = []rune(props.delimiter)[0]
+	}
+
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int)
+	for i, name := range lines[0] {
+		for field, column := range columns {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["date"]; !ok {
+		return nil, fmt.Errorf("CSV has no column '%s'", columns["date"])
+	}
+
+	days := make([]db.WorkingDay, 0, len(lines)-1)
+	for i, line := range lines[1:] {
+		value := func(field string) string {
+			if pos, ok := index[field]; ok && pos < len(line) {
+				return strings.TrimSpace(line[pos])
+			}
+			return ""
+		}
+
+		wd, err := csvDay(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		days = append(days, wd)
+	}
+
+	return combineDays(days), nil
+}
+
+// csvDay creates a working day from the values of a CSV line
+func csvDay(value func(field string) string) (db.WorkingDay, error) {
+	d, err := parseDate(value("date"))
+	if err != nil {
+		return db.WorkingDay{}, err
+	}
+	date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Now().Location())
+
+	wd := db.WorkingDay{Date: date, Type: db.Work, Note: value("note")}
+	if t := value("type"); t != "" {
+		if wd.Type, err = db.ParseDayType(t); err != nil {
+			return wd, err
+		}
+	}
+	if b := value("break_minutes"); b != "" {
+		if wd.Brk, err = strconv.Atoi(b); err != nil {
+			return wd, fmt.Errorf("invalid break '%s'", b)
+		}
+	}
+
+	if value("start") == "" {
+		return wd, nil
+	}
+
+	start, err := parseTimestamp(value("start"), date, date)
+	if err != nil {
+		return wd, err
+	}
+	session := db.Session{Start: start, Project: value("project"), Tags: db.JoinTags(strings.Split(value("tags"), ","))}
+	if value("end") != "" {
+		end, err := parseTimestamp(value("end"), date, start)
+		if err != nil {
+			return wd, err
+		}
+		session.End = &end
+	}
+	wd.Sessions = []db.Session{session}
+
+	return wd, nil
+}
+
+// parseTimestamp parses an ISO-8601 timestamp or a time in the format "hh:mm" on the date. A time before the
+// start lies on the following day like the end of a night shift.
+func parseTimestamp(value string, date time.Time, start time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if !timeOfDay.MatchString(value) {
+		return time.Time{}, fmt.Errorf("invalid time '%s' - use ISO-8601 or hh:mm", value)
+	}
+	t, err := when.Clock(value, date, time.Now())
+	if err != nil {
+		return t, fmt.Errorf("invalid time '%s' - use ISO-8601 or hh:mm", value)
+	}
+	return when.EndAfter(start, t), nil
+}
+
+// combineDays merges working days of the same date into one. The first day provides type, break and note.
+func combineDays(days []db.WorkingDay) []db.WorkingDay {
+	combined := make([]db.WorkingDay, 0, len(days))
+	index := make(map[string]int)
+
+	for _, wd := range days {
+		key := wd.Date.Format("2006-01-02")
+		if pos, ok := index[key]; ok {
+			combined[pos].Sessions = append(combined[pos].Sessions, wd.Sessions...)
+			continue
+		}
+		index[key] = len(combined)
+		combined = append(combined, wd)
+	}
+
+	return combined
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importCmdProps.format, "format", "f", "", "Format of FILE: csv or json. (default: extension of FILE)")
+	importCmd.Flags().StringVarP(&importCmdProps.delimiter, "delimiter", "", ",", "Delimiter of the CSV columns.")
+	importCmd.Flags().StringArrayVarP(&importCmdProps.mapping, "map", "m", nil, "Maps a field to a CSV column. Format: field=column")
+	importCmd.Flags().StringVarP(&importCmdProps.conflict, "conflict", "c", conflictSkip, "Handling of existing days: skip, overwrite or merge.")
+	importCmd.Flags().BoolVarP(&importCmdProps.dryRun, "dry-run", "", false, "Shows what would be imported without changing anything.")
+}
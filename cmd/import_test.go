@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeImportFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunImportCSV(t *testing.T) {
+
+	repo := newFakeRepo()
+	path := writeImportFile(t, "days.csv", `Datum;Beginn;Ende;Pause;Projekt;Notiz
+2021-03-01;08:00;12:00;30;acme;Monday
+2021-03-01;13:00;17:00;30;acme;Monday
+02.03.2021;09:00;17:00;45;;Tuesday
+`)
+	props := ImportCmdProps{
+		delimiter: ";",
+		mapping:   []string{"date=Datum", "start=Beginn", "end=Ende", "break_minutes=Pause", "project=Projekt", "note=Notiz"},
+		conflict:  conflictSkip,
+	}
+
+	// Dotted dates do not depend on the locale
+	old := cfg
+	cfg.Locale = "us"
+	defer func() { cfg = old }()
+
+	testOut := strings.Builder{}
+	if err := runImport(path, props, &testOut, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.Now().Location())
+	wd := repo.loadDay(day)
+	if wd == nil || len(wd.Sessions) != 2 || wd.Brk != 30 || wd.Note != "Monday" || wd.Sessions[1].Project != "acme" {
+		t.Fatalf("Did not import monday correctly: %v", wd)
+	}
+	if repo.loadProject("acme") == nil {
+		t.Fatal("Did not add unknown project")
+	}
+
+	day = day.AddDate(0, 0, 1)
+	wd = repo.loadDay(day)
+	if wd == nil || wd.Worked() != 7*time.Hour+15*time.Minute {
+		t.Fatalf("Did not import tuesday correctly: %v", wd)
+	}
+}
+
+func TestRunImportJSONRoundTrip(t *testing.T) {
+
+	repo := newFakeRepo()
+	for _, props := range []RootCmdProps{
+		{date: "2021-03-01", start: "08:00", end: "16:30", brk: breakOf(30), note: "exported"},
+		{date: "2021-03-02", start: "08:00", end: "12:00"},
+	} {
+		if err := runRoot(props, &repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withOutput(t, "json")
+	exported := strings.Builder{}
+	if err := runList(ListCmdProps{startDate: "2021-03-01", endDate: "2021-03-31"}, &exported, &repo); err != nil {
+		t.Fatal(err)
+	}
+	path := writeImportFile(t, "days.json", exported.String())
+
+	// Existing days are skipped by default
+	target := newFakeRepo()
+	changed := time.Date(2021, 3, 2, 7, 0, 0, 0, time.Now().Location())
+	target.Insert(newWorkingDay(changed, changed.Add(time.Hour), 0, "kept"))
+
+	testOut := strings.Builder{}
+	backups := 0
+	backup := func() { backups++ }
+	if err := runImport(path, ImportCmdProps{conflict: conflictSkip}, &testOut, &target, backup); err != nil || backups != 0 {
+		t.Fatalf("Expected an import without backup but got %d backups (%v)", backups, err)
+	}
+	if wd := target.loadDay(changed); wd.Note != "kept" || len(wd.Sessions) != 1 {
+		t.Fatalf("Skip changed existing day: %s", wd)
+	}
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.Now().Location())
+	if wd := target.loadDay(day); wd == nil || wd.Note != "exported" || wd.Worked() != 8*time.Hour {
+		t.Fatalf("Did not import exported day: %v", wd)
+	}
+
+	// Merge adds missing sessions
+	if err := runImport(path, ImportCmdProps{conflict: conflictMerge}, &testOut, &target, backup); err != nil || backups != 1 {
+		t.Fatalf("Expected a backup before the merge but got %d backups (%v)", backups, err)
+	}
+	if wd := target.loadDay(changed); wd.Note != "kept" || len(wd.Sessions) != 2 {
+		t.Fatalf("Merge did not add the session: %s", wd)
+	}
+
+	// Overwrite replaces the day
+	if err := runImport(path, ImportCmdProps{conflict: conflictOverwrite}, &testOut, &target, noBackup); err != nil {
+		t.Fatal(err)
+	}
+	if wd := target.loadDay(changed); wd.Note != "" || len(wd.Sessions) != 1 || wd.Start().Hour() != 8 {
+		t.Fatalf("Overwrite did not replace the day: %s", wd)
+	}
+}
+
+func TestRunImportDryRunAndErrors(t *testing.T) {
+
+	repo := newFakeRepo()
+	path := writeImportFile(t, "days.csv", "date,start,end\n2021-03-01,08:00,16:00\n")
+
+	withOutput(t, "table")
+	testOut := strings.Builder{}
+	if err := runImport(path, ImportCmdProps{conflict: conflictSkip, dryRun: true}, &testOut, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data) != 0 || !strings.Contains(testOut.String(), "insert") {
+		t.Fatalf("Dry run changed data or did not preview: %s", testOut.String())
+	}
+
+	// Invalid lines prevent the whole import
+	path = writeImportFile(t, "days.csv", "date,start,end\n2021-03-01,08:00,16:00\n2021-03-02,08:00,25:00\n")
+	if err := runImport(path, ImportCmdProps{conflict: conflictSkip}, &testOut, &repo, noBackup); err == nil || len(repo.data) != 0 {
+		t.Fatal("Imported a file with an invalid line")
+	}
+
+	if err := runImport(path, ImportCmdProps{conflict: "replace"}, &testOut, &repo, noBackup); err == nil {
+		t.Fatal("Accepted unknown conflict policy")
+	}
+	if err := runImport(path, ImportCmdProps{conflict: conflictSkip, mapping: []string{"day=Datum"}}, &testOut, &repo, noBackup); err == nil {
+		t.Fatal("Accepted mapping of unknown field")
+	}
+	if err := runImport(path, ImportCmdProps{conflict: conflictSkip, format: "xml"}, &testOut, &repo, noBackup); err == nil {
+		t.Fatal("Accepted unknown format")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+
+	date := time.Date(2021, 3, 1, 0, 0, 0, 0, time.Now().Location())
+	start, err := parseTimestamp("22:00", date, date)
+	if err != nil || !start.Equal(date.Add(22*time.Hour)) {
+		t.Fatalf("Unexpected start %v (%v)", start, err)
+	}
+	if end, err := parseTimestamp("06:00", date, start); err != nil || !end.Equal(date.Add(30*time.Hour)) {
+		t.Fatalf("Expected the end on the following day but got %v (%v)", end, err)
+	}
+	if end, err := parseTimestamp("2021-03-02T06:00:00Z", date, start); err != nil || !end.Equal(time.Date(2021, 3, 2, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected ISO-8601 end %v (%v)", end, err)
+	}
+	for _, value := range []string{"25:00", "now", "+1h", "now-15m"} {
+		if _, err := parseTimestamp(value, date, start); err == nil || !strings.Contains(err.Error(), "invalid time '"+value+"'") {
+			t.Fatalf("Accepted the time %q: %v", value, err)
+		}
+	}
+}
@@ -24,10 +24,10 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
 	"github.com/corka149/timed/db"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
-	jww "github.com/spf13/jwalterweatherman"
 	"io"
 	"os"
 	"time"
@@ -43,12 +43,12 @@ var (
 	listCmd = &cobra.Command{
 		Use:   "list",
 		Short: "List working days",
-		Long:  "List working days for a given range. By default it looks 30 days back",
+		Long:  "List working days for a given range. By default it looks as many days back as configured by list_days (default: 30)",
 		Run: func(cmd *cobra.Command, args []string) {
-			repo := db.NewRepo(DbPath())
+			repo := openRepo()
 
 			if err := runList(listCmdProps, os.Stdout, repo); err != nil {
-				jww.ERROR.Fatal(err)
+				fail(err)
 			}
 		},
 	}
@@ -61,6 +61,9 @@ var (
 type ListCmdProps struct {
 	startDate string
 	endDate   string
+
+	project string
+	tag     string
 }
 
 // ===================
@@ -68,36 +71,63 @@ type ListCmdProps struct {
 // ===================
 
 func runList(props ListCmdProps, output io.Writer, repo db.Repo) error {
-	start, err := parseDateOrDefault(props.startDate)
+	workingDays, err := listRange(props.startDate, props.endDate, repo)
 
-	if props.startDate == "" {
-		defaultStart := start.Add(-1 * time.Hour * 24 * 30)
-		start = &defaultStart
+	if err != nil {
+		return err
 	}
 
+	schedule, err := repo.Schedule()
 	if err != nil {
 		return err
 	}
 
-	end, err := parseDateOrDefault(props.endDate)
+	workingDays = filterDays(workingDays, props.project, props.tag)
+	return renderTable(workingDays, schedule, output)
+}
+
+// listRange loads the working days between both dates. The start defaults to list_days before the end.
+func listRange(startDate string, endDate string, repo db.Repo) ([]db.WorkingDay, error) {
+	start, err := parseDateOrDefault(startDate)
+
+	if startDate == "" {
+		defaultStart := start.Add(-1 * time.Hour * 24 * time.Duration(cfg.ListDays))
+		start = &defaultStart
+	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	workingDays, err := repo.ListRange(start, end)
+	end, err := parseDateOrDefault(endDate)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	renderTable(workingDays, output)
+	return repo.ListRange(start, end)
+}
 
-	return nil
+// filterDays keeps the working days with at least one session matching project and tag
+func filterDays(workingDays []db.WorkingDay, project string, tag string) []db.WorkingDay {
+	if project == "" && tag == "" {
+		return workingDays
+	}
+
+	filtered := make([]db.WorkingDay, 0, len(workingDays))
+	for _, wd := range workingDays {
+		for _, s := range wd.Sessions {
+			if s.Matches(project, tag) {
+				filtered = append(filtered, wd)
+				break
+			}
+		}
+	}
+	return filtered
 }
 
 func parseDateOrDefault(dateStr string) (*time.Time, error) {
-	date, err := time.Parse("2006-01-02", dateStr)
+	date, err := parseDate(dateStr)
 
 	if err != nil && dateStr != "" {
 		return nil, err
@@ -110,22 +140,30 @@ func parseDateOrDefault(dateStr string) (*time.Time, error) {
 	return &date, nil
 }
 
-func renderTable(workingDays []db.WorkingDay, output io.Writer) {
+func renderTable(workingDays []db.WorkingDay, schedule db.Schedule, output io.Writer) error {
 	t := table.NewWriter()
-	t.SetOutputMirror(output)
 
-	header := table.Row{"Start", "End", "Break", "Note"}
+	header := table.Row{"Date", "Type", "Sessions", "Break", "Worked", "Target", "Note"}
 	t.AppendHeader(header)
 
+	records := make(dayRecords, 0, len(workingDays))
+	var worked, target time.Duration
 	for _, wd := range workingDays {
-		t.AppendRow(wd.ToRow())
+		dayTarget := schedule.TargetOfDay(&wd)
+		t.AppendRow(wd.ToRow(dayTarget, dateLayout()))
+		records = append(records, wd.ToRecord(dayTarget))
+		worked += wd.Worked()
+		target += dayTarget
 	}
+	t.AppendFooter(table.Row{"", "", "", "Total", fmt.Sprintf("%.2f", worked.Hours()), fmt.Sprintf("%.2f", target.Hours()), ""})
 
-	t.Render()
+	return render(output, t, records)
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
-	listCmd.Flags().StringVarP(&listCmdProps.startDate, "start", "s", "", `Start date of selection. Format: "yyyy-mm-dd" -> E.g. 2019-03-28. (default: today)`)
-	listCmd.Flags().StringVarP(&listCmdProps.endDate, "end", "e", "", `End date of selection. Format: "yyyy-mm-dd" -> E.g. 2019-03-28. (default: today)`)
+	listCmd.Flags().StringVarP(&listCmdProps.startDate, "start", "s", "", `Start date of selection. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	listCmd.Flags().StringVarP(&listCmdProps.endDate, "end", "e", "", `End date of selection. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	listCmd.Flags().StringVarP(&listCmdProps.project, "project", "p", "", "Shows only days with sessions of the project.")
+	listCmd.Flags().StringVarP(&listCmdProps.tag, "tag", "t", "", "Shows only days with sessions having the tag.")
 }
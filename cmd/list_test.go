@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/corka149/timed/db"
 	"strings"
 	"testing"
 	"time"
@@ -14,25 +13,10 @@ const (
 
 func TestListDays(t *testing.T) {
 	// Arrange
-	repo := FakeRepo{make(map[string]db.WorkingDay)}
-	repo.Insert(db.WorkingDay{
-		Start: time.Now().Add(PastDay * 5),
-		End:   time.Now().Add(PastDay * 5),
-		Brk:   30,
-		Note:  "foo",
-	})
-	repo.Insert(db.WorkingDay{
-		Start: time.Now(),
-		End:   time.Now(),
-		Brk:   30,
-		Note:  "bar",
-	})
-	repo.Insert(db.WorkingDay{
-		Start: time.Now().Add(Day * 5),
-		End:   time.Now().Add(Day * 5),
-		Brk:   30,
-		Note:  "foo",
-	})
+	repo := newFakeRepo()
+	repo.Insert(newWorkingDay(time.Now().Add(PastDay*5), time.Now().Add(PastDay*5), 30, "foo"))
+	repo.Insert(newWorkingDay(time.Now(), time.Now(), 30, "bar"))
+	repo.Insert(newWorkingDay(time.Now().Add(Day*5), time.Now().Add(Day*5), 30, "foo"))
 
 	props := ListCmdProps{
 		startDate: time.Now().Add(PastDay).Format("2006-01-02"),
@@ -0,0 +1,245 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	offCmdProps      = OffCmdProps{}
+	vacationCmdProps = VacationCmdProps{}
+
+	offCmd = &cobra.Command{
+		Use:   "off TYPE [FROM] [TO]",
+		Short: "Marks days as day off",
+		Long: `Off marks all days from FROM to TO as TYPE. TYPE is one of vacation, sick, holiday, comp-time or work.
+Vacation, sick days and holidays meet the target of a day while comp-time consumes it. Days without target are skipped.
+FROM defaults to today and TO defaults to FROM. E.g. 2019-03-28, yesterday, "last friday" or -2d.`,
+		Args: cobra.RangeArgs(1, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			offCmdProps.dayType = args[0]
+			if len(args) > 1 {
+				offCmdProps.from = args[1]
+			}
+			if len(args) > 2 {
+				offCmdProps.to = args[2]
+			}
+
+			repo := openRepo()
+			if err := runOff(offCmdProps, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	vacationCmd = &cobra.Command{
+		Use:   "vacation",
+		Short: "Shows the vacation balance of a year",
+		Long:  "Vacation compares the vacation days of a year with the yearly allowance configured by vacation_days.",
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+			if err := runVacation(vacationCmdProps, cfg.VacationDays, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// OffCmdProps represents all local properties of the off command
+type OffCmdProps struct {
+	dayType string
+	from    string
+	to      string
+
+	note string
+}
+
+// VacationCmdProps represents all local properties of the vacation command
+type VacationCmdProps struct {
+	year int
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// vacationBalance contains the days off of a year
+type vacationBalance struct {
+	Year      int `json:"year"`
+	Allowance int `json:"allowance"`
+	Taken     int `json:"taken"`
+	Planned   int `json:"planned"`
+	Remaining int `json:"remaining"`
+	Sick      int `json:"sick"`
+	Holidays  int `json:"holidays"`
+	CompTime  int `json:"comp_time"`
+}
+
+// runOff sets the type of all days in the range.
+func runOff(props OffCmdProps, repo db.Repo) error {
+	dayType, err := db.ParseDayType(props.dayType)
+	if err != nil {
+		return err
+	}
+
+	from, err := parseDateOrDefault(props.from)
+	if err != nil {
+		return err
+	}
+
+	to := from
+	if props.to != "" {
+		if to, err = parseDateOrDefault(props.to); err != nil {
+			return err
+		}
+	}
+	if to.Before(*from) {
+		return errors.New("end of range is before its start")
+	}
+
+	schedule, err := repo.Schedule()
+	if err != nil {
+		return err
+	}
+	marked, skipped := 0, 0
+
+	// The whole range is marked or nothing
+	err = repo.Transaction(func(tx db.Repo) error {
+		for d := *from; !d.After(*to); d = d.AddDate(0, 0, 1) {
+			if dayType != db.Work && schedule.TargetOf(d) == 0 {
+				skipped++
+				continue
+			}
+
+			wd, err := loadDay(tx, &d)
+			if err != nil {
+				return err
+			}
+			if wd != nil {
+				wd.Type = dayType
+				if props.note != "" {
+					wd.Note = props.note
+				}
+				err = tx.UpdateDay(*wd)
+			} else {
+				err = tx.Insert(db.WorkingDay{
+					Date: time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Now().Location()),
+					Type: dayType,
+					Note: props.note,
+				})
+			}
+			if err != nil {
+				return err
+			}
+			marked++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Marked %d days as %s (skipped %d days without target)", marked, dayType, skipped)
+	return nil
+}
+
+// runVacation renders the vacation balance and the other days off of a year.
+func runVacation(props VacationCmdProps, allowance int, output io.Writer, repo db.Repo) error {
+	year := props.year
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Now().Location())
+	end := time.Date(year, 12, 31, 23, 59, 59, 0, time.Now().Location())
+	workingDays, err := repo.ListRange(&start, &end)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	count := make(map[db.DayType]int)
+	planned := 0
+	for _, wd := range workingDays {
+		if wd.Kind() == db.Vacation && wd.Date.After(now) {
+			planned++
+			continue
+		}
+		count[wd.Kind()]++
+	}
+
+	balance := vacationBalance{
+		Year:      year,
+		Allowance: allowance,
+		Taken:     count[db.Vacation],
+		Planned:   planned,
+		Remaining: allowance - count[db.Vacation] - planned,
+		Sick:      count[db.Sick],
+		Holidays:  count[db.Holiday],
+		CompTime:  count[db.CompTime],
+	}
+
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("Vacation %d", year))
+	t.AppendRows([]table.Row{
+		{"Allowance", balance.Allowance},
+		{"Taken", balance.Taken},
+		{"Planned", balance.Planned},
+		{"Remaining", balance.Remaining},
+	})
+	t.AppendSeparator()
+	t.AppendRows([]table.Row{
+		{"Sick", balance.Sick},
+		{"Public holidays", balance.Holidays},
+		{"Comp-time", balance.CompTime},
+	})
+
+	return render(output, t, balance)
+}
+
+func init() {
+	rootCmd.AddCommand(offCmd)
+	offCmd.Flags().StringVarP(&offCmdProps.note, "note", "n", "", "Takes a note and add it to all days. Default: ''")
+
+	rootCmd.AddCommand(vacationCmd)
+	vacationCmd.Flags().IntVarP(&vacationCmdProps.year, "year", "y", 0, "Year of the balance. (default: current year)")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+)
+
+func TestRunOff(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	// Existing working day on wednesday
+	start := time.Date(2021, 5, 5, 8, 0, 0, 0, time.Now().Location())
+	repo.Insert(newWorkingDay(start, start.Add(4*time.Hour), 0, "half"))
+
+	// Monday to sunday
+	err := runOff(OffCmdProps{dayType: "vacation", from: "2021-05-03", to: "2021-05-09", note: "Beach"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Default schedule has a target on every day
+	if len(repo.data) != 7 {
+		t.Fatalf("Expected 7 days off but got %d", len(repo.data))
+	}
+
+	wd := repo.loadDay(start)
+	if wd.Kind() != db.Vacation || wd.Note != "Beach" || len(wd.Sessions) != 1 {
+		t.Fatalf("Existing day was not marked correctly: %s", wd)
+	}
+}
+
+func TestRunOffSkipsDaysWithoutTarget(t *testing.T) {
+
+	repo := newFakeRepo()
+	if err := runTargetSet(TargetSetCmdProps{from: "2021-01-01", hours: 8, weekdays: []string{"mon", "tue", "wed", "thu", "fri"}}, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runOff(OffCmdProps{dayType: "sick", from: "2021-05-03", to: "2021-05-09"}, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data) != 5 {
+		t.Fatalf("Expected 5 sick days but got %d", len(repo.data))
+	}
+}
+
+func TestRunOffWithErrors(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	if err := runOff(OffCmdProps{dayType: "party"}, &repo); err == nil {
+		t.Fatal("Accepted unknown day type")
+	}
+	if err := runOff(OffCmdProps{dayType: "vacation", from: "2021-05-09", to: "2021-05-03"}, &repo); err == nil {
+		t.Fatal("Accepted range with end before start")
+	}
+}
+
+func TestRunVacation(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	year := time.Now().Year() - 1
+	from := time.Date(year, 8, 2, 0, 0, 0, 0, time.Now().Location())
+	to := from.AddDate(0, 0, 2)
+	if err := runOff(OffCmdProps{dayType: "vacation", from: from.Format("2006-01-02"), to: to.Format("2006-01-02")}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := runOff(OffCmdProps{dayType: "sick", from: to.AddDate(0, 0, 1).Format("2006-01-02")}, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	testOut := strings.Builder{}
+	if err := runVacation(VacationCmdProps{year: year}, 30, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{"|Taken|3|", "|Remaining|27|", "|Sick|1|"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected '%s' in %s", expected, testOut.String())
+		}
+	}
+}
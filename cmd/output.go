@@ -0,0 +1,148 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corka149/timed/config"
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+// outputFormat is the output format passed by flag
+var outputFormat string
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// csvRecords is implemented by data which has an own CSV form instead of the CSV form of its table
+type csvRecords interface {
+	csvRecords() [][]string
+}
+
+// dayRecords are the records of listed working days
+type dayRecords []db.DayRecord
+
+// csvRecords renders one line per session, so every value has its own column
+func (records dayRecords) csvRecords() [][]string {
+	lines := [][]string{{"date", "type", "start", "end", "break_minutes", "worked_minutes", "target_minutes", "project", "tags", "note"}}
+
+	for _, r := range records {
+		sessions := r.Sessions
+		if len(sessions) == 0 {
+			// Days off have no sessions but still need a line
+			sessions = []db.SessionRecord{{}}
+		}
+
+		for _, s := range sessions {
+			start, end := "", ""
+			if !s.Start.IsZero() {
+				start = s.Start.Format(time.RFC3339)
+			}
+			if s.End != nil {
+				end = s.End.Format(time.RFC3339)
+			}
+
+			lines = append(lines, []string{
+				r.Date, string(r.Type), start, end, strconv.Itoa(r.BreakMinutes), strconv.Itoa(r.WorkedMinutes),
+				strconv.Itoa(r.TargetMinutes), s.Project, strings.Join(s.Tags, ","), r.Note,
+			})
+		}
+	}
+
+	return lines
+}
+
+// format returns the output format from the flag or the configuration
+func format() (string, error) {
+	if outputFormat == "" {
+		return cfg.Output, nil
+	}
+
+	for _, f := range config.Outputs {
+		if f == outputFormat {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format '%s' - use one of %s", outputFormat, strings.Join(config.Outputs, ", "))
+}
+
+// machineReadable reports whether the output is meant for scripts
+func machineReadable() bool {
+	f, _ := format()
+	return f == "json" || f == "csv"
+}
+
+// dateLayout returns ISO dates for scripts and the date layout of the locale otherwise
+func dateLayout() string {
+	if machineReadable() {
+		return "2006-01-02"
+	}
+	return cfg.DateLayout()
+}
+
+// render writes the table or the data in the output format. JSON always uses data.
+func render(output io.Writer, t table.Writer, data interface{}) error {
+	f, err := format()
+	if err != nil {
+		return err
+	}
+
+	t.SetOutputMirror(output)
+
+	switch f {
+	case "json":
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case "csv":
+		if records, ok := data.(csvRecords); ok {
+			w := csv.NewWriter(output)
+			if err = w.WriteAll(records.csvRecords()); err != nil {
+				return err
+			}
+			return w.Error()
+		}
+		t.RenderCSV()
+	case "markdown":
+		t.RenderMarkdown()
+	case "html":
+		t.RenderHTML()
+	default:
+		t.Render()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withOutput sets the output format for the rest of the test
+func withOutput(t *testing.T, f string) {
+	old := outputFormat
+	outputFormat = f
+	t.Cleanup(func() { outputFormat = old })
+}
+
+func TestListOutputFormats(t *testing.T) {
+
+	repo := newFakeRepo()
+	if err := runProjectAdd("acme", &repo); err != nil {
+		t.Fatal(err)
+	}
+	props := RootCmdProps{date: "2020-08-13", start: "10:00", end: "18:00", brk: breakOf(30), note: "Note", project: "acme", tags: []string{"a", "b"}}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	listProps := ListCmdProps{startDate: "2020-08-01", endDate: "2020-08-31"}
+
+	// JSON
+	withOutput(t, "json")
+	testOut := strings.Builder{}
+	if err := runList(listProps, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal([]byte(testOut.String()), &records); err != nil {
+		t.Fatalf("Invalid JSON %s: %s", testOut.String(), err)
+	}
+	if len(records) != 1 || records[0]["date"] != "2020-08-13" || records[0]["worked_minutes"] != 450.0 || records[0]["note"] != "Note" {
+		t.Fatalf("Unexpected JSON %s", testOut.String())
+	}
+	session := records[0]["sessions"].([]interface{})[0].(map[string]interface{})
+	start, err := time.Parse(time.RFC3339, session["start"].(string))
+	if err != nil || start.Hour() != 10 || session["project"] != "acme" {
+		t.Fatalf("Unexpected session in JSON %s", testOut.String())
+	}
+
+	// CSV
+	withOutput(t, "csv")
+	testOut.Reset()
+	if err := runList(listProps, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := csv.NewReader(strings.NewReader(testOut.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0][0] != "date" || lines[1][0] != "2020-08-13" || lines[1][8] != "a,b" {
+		t.Fatalf("Unexpected CSV %s", testOut.String())
+	}
+
+	// Markdown
+	withOutput(t, "markdown")
+	testOut.Reset()
+	if err := runList(listProps, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(testOut.String(), "| Date |") {
+		t.Fatalf("Unexpected markdown %s", testOut.String())
+	}
+
+	// Unknown
+	withOutput(t, "pdf")
+	if err := runList(listProps, &testOut, &repo); err == nil {
+		t.Fatal("Accepted unknown output format")
+	}
+}
+
+func TestReportOutputFormats(t *testing.T) {
+
+	repo := newFakeRepo()
+	if err := runIn(ClockCmdProps{at: "00:00"}, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	withOutput(t, "json")
+	testOut := strings.Builder{}
+	if err := printReport(&testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	status := statusRecord{}
+	if err := json.Unmarshal([]byte(testOut.String()), &status); err != nil {
+		t.Fatalf("Invalid JSON %s: %s", testOut.String(), err)
+	}
+	if status.RunningSince == nil || status.WorkedTodayMinutes == nil || status.OvertimeMinutes != 123 {
+		t.Fatalf("Unexpected JSON %s", testOut.String())
+	}
+
+	withOutput(t, "html")
+	testOut.Reset()
+	err := runSummary(SummaryCmdProps{by: "project"}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(testOut.String(), "<table") {
+		t.Fatalf("Unexpected HTML %s", testOut.String())
+	}
+}
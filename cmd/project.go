@@ -0,0 +1,181 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	projectListCmdProps = ProjectListCmdProps{}
+
+	projectCmd = &cobra.Command{
+		Use:   "project",
+		Short: "Manages projects",
+		Long:  "Project manages the projects sessions can be assigned to with --project.",
+	}
+
+	projectAddCmd = &cobra.Command{
+		Use:   "add NAME",
+		Short: "Adds the project NAME",
+		Long:  "Add creates a new project which can be assigned to sessions. An archived project with the same name is reactivated.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runProjectAdd(args[0], repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	projectListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists projects",
+		Long:  "List shows all active projects. Archived projects are shown with --all.",
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runProjectList(projectListCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	projectArchiveCmd = &cobra.Command{
+		Use:   "archive NAME",
+		Short: "Archives the project NAME",
+		Long:  "Archive hides a project from the list and prevents assigning it to new sessions. Recorded sessions keep it.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runProjectArchive(args[0], repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ProjectListCmdProps represents all local properties of the project list command
+type ProjectListCmdProps struct {
+	all bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// projectRecord is the machine readable form of a project
+type projectRecord struct {
+	Name     string `json:"name"`
+	Archived bool   `json:"archived"`
+}
+
+func runProjectAdd(name string, repo db.Repo) error {
+	if name == "" {
+		return fmt.Errorf("project name must not be empty")
+	}
+
+	p, err := repo.LoadProject(name)
+	if errors.Is(err, db.ErrNotFound) {
+		p, err = &db.Project{Name: name}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if p.ID != 0 && !p.Archived {
+		return conflict("project '%s' already exists", name)
+	}
+
+	p.Archived = false
+	if err = repo.SaveProject(*p); err != nil {
+		return err
+	}
+	jww.FEEDBACK.Printf("Added project '%s'", name)
+	return nil
+}
+
+func runProjectList(props ProjectListCmdProps, output io.Writer, repo db.Repo) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Project", "Archived"})
+
+	projects, err := repo.Projects()
+	if err != nil {
+		return err
+	}
+
+	records := make([]projectRecord, 0)
+	for _, p := range projects {
+		if p.Archived && !props.all {
+			continue
+		}
+		t.AppendRow(table.Row{p.Name, p.Archived})
+		records = append(records, projectRecord{Name: p.Name, Archived: p.Archived})
+	}
+
+	return render(output, t, records)
+}
+
+func runProjectArchive(name string, repo db.Repo) error {
+	p, err := repo.LoadProject(name)
+	if errors.Is(err, db.ErrNotFound) {
+		return notFound("unknown project '%s'", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.Archived = true
+	if err = repo.SaveProject(*p); err != nil {
+		return err
+	}
+	jww.FEEDBACK.Printf("Archived project '%s'", name)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+	projectCmd.AddCommand(projectAddCmd)
+	projectCmd.AddCommand(projectListCmd)
+	projectCmd.AddCommand(projectArchiveCmd)
+
+	projectListCmd.Flags().BoolVarP(&projectListCmdProps.all, "all", "a", false, "Shows archived projects too.")
+}
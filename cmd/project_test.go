@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProject(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	if err := runProjectAdd("acme", &repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := runProjectAdd("acme", &repo); err == nil {
+		t.Fatal("Added project twice")
+	}
+
+	// Sessions can be assigned to the project
+	props := RootCmdProps{date: "2020-08-13", start: "10:00", end: "12:00", project: "acme", tags: []string{"bug", "call"}}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+	wd := repo.loadDay(day)
+	if wd.Sessions[0].Project != "acme" || !wd.Sessions[0].HasTag("call") {
+		t.Fatalf("Session was not assigned to project and tags: %s", wd)
+	}
+
+	props.project = "unknown"
+	if err := runRoot(props, &repo); err == nil {
+		t.Fatal("Accepted unknown project")
+	}
+
+	// Archived projects are hidden and cannot be assigned
+	if err := runProjectArchive("acme", &repo); err != nil {
+		t.Fatal(err)
+	}
+	props.project = "acme"
+	if err := runRoot(props, &repo); err == nil {
+		t.Fatal("Accepted archived project")
+	}
+
+	testOut := strings.Builder{}
+	if err := runProjectList(ProjectListCmdProps{}, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(testOut.String(), "acme") {
+		t.Fatalf("Listed archived project: %s", testOut.String())
+	}
+
+	testOut.Reset()
+	if err := runProjectList(ProjectListCmdProps{all: true}, &testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(testOut.String(), "acme") {
+		t.Fatalf("Did not list archived project with all: %s", testOut.String())
+	}
+
+	// Adding again reactivates
+	if err := runProjectAdd("acme", &repo); err != nil {
+		t.Fatal(err)
+	}
+	if p := repo.loadProject("acme"); p.Archived {
+		t.Fatal("Project was not reactivated")
+	}
+}
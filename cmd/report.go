@@ -0,0 +1,253 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	reportCmdProps = ReportCmdProps{}
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Reports the hours of a week, month or year",
+		Long: `Report aggregates worked hours, breaks, target and overtime of the week, month or year containing DATE.
+Week and month are reported per day, a year per week. The balance starts with the overtime before the period.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runReport(reportCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ReportCmdProps represents all local properties of the report command
+type ReportCmdProps struct {
+	date string
+
+	week  bool
+	month bool
+	year  bool
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// reportRecord is the machine readable form of a report
+type reportRecord struct {
+	Start string            `json:"start"`
+	End   string            `json:"end"`
+	Rows  []reportRowRecord `json:"rows"`
+	Total reportRowRecord   `json:"total"`
+}
+
+// reportRowRecord is the machine readable form of a day or week of a report
+type reportRowRecord struct {
+	Period          string `json:"period"`
+	WorkedMinutes   int    `json:"worked_minutes"`
+	BreakMinutes    int    `json:"break_minutes"`
+	TargetMinutes   int    `json:"target_minutes"`
+	OvertimeMinutes int    `json:"overtime_minutes"`
+	BalanceMinutes  int    `json:"balance_minutes"`
+}
+
+// reportRow contains the aggregated values of a day or a week
+type reportRow struct {
+	label    string
+	worked   time.Duration
+	brk      time.Duration
+	target   time.Duration
+	overtime time.Duration
+}
+
+func runReport(props ReportCmdProps, output io.Writer, repo db.Repo) error {
+	selected := 0
+	for _, p := range []bool{props.week, props.month, props.year} {
+		if p {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return errors.New("only one of --week, --month and --year can be used")
+	}
+
+	date, err := parseDateOrDefault(props.date)
+	if err != nil {
+		return err
+	}
+
+	start, end := weekOf(*date)
+	if props.month {
+		start, end = monthOf(*date)
+	} else if props.year {
+		start, end = yearOf(*date)
+	}
+
+	before := start.Add(-time.Second)
+	previous, err := repo.ListRange(&time.Time{}, &before)
+	if err != nil {
+		return err
+	}
+
+	workingDays, err := repo.ListRange(&start, &end)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := repo.Schedule()
+	if err != nil {
+		return err
+	}
+	rows := aggregate(workingDays, schedule, props.year)
+	return renderReport(start, end, rows, schedule.Overtime(previous), output)
+}
+
+// aggregate sums up the working days per day or per ISO week in chronological order
+func aggregate(workingDays []db.WorkingDay, schedule db.Schedule, perWeek bool) []reportRow {
+	rows := make([]reportRow, 0)
+	index := make(map[string]int)
+
+	sorted := make([]db.WorkingDay, len(workingDays))
+	copy(sorted, workingDays)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	for _, wd := range sorted {
+		label := fmt.Sprintf("%s %s", wd.Date.Format("Mon"), wd.Date.Format(dateLayout()))
+		if machineReadable() {
+			label = wd.Date.Format("2006-01-02")
+		}
+		if perWeek {
+			year, week := wd.Date.ISOWeek()
+			label = fmt.Sprintf("%d-W%02d", year, week)
+		}
+
+		pos, ok := index[label]
+		if !ok {
+			pos = len(rows)
+			index[label] = pos
+			rows = append(rows, reportRow{label: label})
+		}
+
+		target := schedule.TargetOfDay(&wd)
+		rows[pos].worked += wd.Worked()
+		rows[pos].brk += time.Duration(wd.Brk) * time.Minute
+		rows[pos].target += target
+		rows[pos].overtime += wd.Worked() - target
+	}
+
+	return rows
+}
+
+func renderReport(start time.Time, end time.Time, rows []reportRow, balance time.Duration, output io.Writer) error {
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("%s - %s", start.Format(dateLayout()), end.Format(dateLayout())))
+	t.AppendHeader(table.Row{"Period", "Worked", "Break", "Target", "Overtime", "Balance"})
+
+	record := reportRecord{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02"), Rows: make([]reportRowRecord, 0, len(rows))}
+	total := reportRow{label: "Total"}
+	for _, r := range rows {
+		balance += r.overtime
+		t.AppendRow(table.Row{r.label, hours(r.worked), hours(r.brk), hours(r.target), hours(r.overtime), hours(balance)})
+		record.Rows = append(record.Rows, r.toRecord(balance))
+
+		total.worked += r.worked
+		total.brk += r.brk
+		total.target += r.target
+		total.overtime += r.overtime
+	}
+
+	t.AppendFooter(table.Row{total.label, hours(total.worked), hours(total.brk), hours(total.target), hours(total.overtime), hours(balance)})
+	record.Total = total.toRecord(balance)
+
+	return render(output, t, record)
+}
+
+func (r reportRow) toRecord(balance time.Duration) reportRowRecord {
+	return reportRowRecord{
+		Period:          r.label,
+		WorkedMinutes:   minutes(r.worked),
+		BreakMinutes:    minutes(r.brk),
+		TargetMinutes:   minutes(r.target),
+		OvertimeMinutes: minutes(r.overtime),
+		BalanceMinutes:  minutes(balance),
+	}
+}
+
+func hours(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Hours())
+}
+
+func minutes(d time.Duration) int {
+	return int(d.Round(time.Minute).Minutes())
+}
+
+// weekOf returns the first and last moment of the ISO week of the date
+func weekOf(date time.Time) (time.Time, time.Time) {
+	offset := (int(date.Weekday()) + 6) % 7
+	start := time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, time.Now().Location())
+	return start, start.AddDate(0, 0, 7).Add(-time.Second)
+}
+
+// monthOf returns the first and last moment of the month of the date
+func monthOf(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Now().Location())
+	return start, start.AddDate(0, 1, 0).Add(-time.Second)
+}
+
+// yearOf returns the first and last moment of the year of the date
+func yearOf(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, time.Now().Location())
+	return start, start.AddDate(1, 0, 0).Add(-time.Second)
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportCmdProps.date, "date", "d", "", `Takes a date within the period. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	reportCmd.Flags().BoolVarP(&reportCmdProps.week, "week", "w", false, "Reports the week per day. (default)")
+	reportCmd.Flags().BoolVarP(&reportCmdProps.month, "month", "m", false, "Reports the month per day.")
+	reportCmd.Flags().BoolVarP(&reportCmdProps.year, "year", "y", false, "Reports the year per week.")
+}
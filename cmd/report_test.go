@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunReport(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	// Overtime before the reported week
+	days := []RootCmdProps{
+		{date: "2021-04-30", start: "08:00", end: "17:00", brk: breakOf(0)},
+		{date: "2021-05-03", start: "08:00", end: "17:00", brk: breakOf(30)},
+		{date: "2021-05-04", start: "08:00", end: "15:30", brk: breakOf(30)},
+		{date: "2021-05-10", start: "08:00", end: "18:00", brk: breakOf(0)},
+	}
+	for _, props := range days {
+		if err := runRoot(props, &repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testOut := strings.Builder{}
+	err := runReport(ReportCmdProps{date: "2021-05-05", week: true}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{
+		"|Mon2021-05-03|8.50|0.50|8.00|0.50|1.50|",
+		"|Tue2021-05-04|7.00|0.50|8.00|-1.00|0.50|",
+		"|TOTAL|15.50|1.00|16.00|-0.50|0.50|",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected '%s' in %s", expected, testOut.String())
+		}
+	}
+	if strings.Contains(out, "2021-05-10") || strings.Contains(out, "2021-04-30") {
+		t.Fatalf("Report contains days outside of the week: %s", testOut.String())
+	}
+
+	testOut.Reset()
+	err = runReport(ReportCmdProps{date: "2021-05-05", year: true}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{"|2021-W17|9.00|0.00|8.00|1.00|1.00|", "|2021-W18|15.50|1.00|16.00|-0.50|0.50|", "|2021-W19|10.00|0.00|8.00|2.00|2.50|"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected '%s' in %s", expected, testOut.String())
+		}
+	}
+
+	if err = runReport(ReportCmdProps{week: true, month: true}, &testOut, &repo); err == nil {
+		t.Fatal("Accepted several periods")
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	date := time.Date(2021, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	start, end := weekOf(date)
+	if start.Format("2006-01-02") != "2020-12-28" || end.Format("2006-01-02 15:04:05") != "2021-01-03 23:59:59" {
+		t.Fatalf("Unexpected week %s - %s", start, end)
+	}
+
+	start, end = monthOf(date)
+	if start.Format("2006-01-02") != "2021-01-01" || end.Format("2006-01-02") != "2021-01-31" {
+		t.Fatalf("Unexpected month %s - %s", start, end)
+	}
+
+	start, end = yearOf(date)
+	if start.Format("2006-01-02") != "2021-01-01" || end.Format("2006-01-02") != "2021-12-31" {
+		t.Fatalf("Unexpected year %s - %s", start, end)
+	}
+}
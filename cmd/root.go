@@ -25,10 +25,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
 )
@@ -38,7 +42,7 @@ import (
 // ===================
 
 var (
-	rootCmdProps = RootCmdProps{}
+	rootCmdProps = RootCmdProps{brk: new(int)}
 
 	rootCmd = &cobra.Command{
 		Use:   "timed",
@@ -54,11 +58,14 @@ var (
 	
 		`,
 		Run: func(cmd *cobra.Command, args []string) {
-			repo := db.NewRepo(DbPath())
-			err := runRoot(rootCmdProps, repo)
+			props := rootCmdProps
+			props.brk = changedBreak(cmd, rootCmdProps.brk)
+
+			repo := openRepo()
+			err := runRoot(props, repo)
 
 			if err != nil {
-				jww.ERROR.Fatal(err)
+				fail(err)
 			}
 		},
 	}
@@ -68,21 +75,27 @@ var (
 // ===== PUBLIC =====
 // ==================
 
-// RootCmdProps represents all local properties of timed
+// RootCmdProps represents all local properties of timed. A nil break is not changed.
 type RootCmdProps struct {
-	date  string
-	start string
-	end   string
+	date    string
+	start   string
+	end     string
+	endDate string
 
-	brk  int
+	brk  *int
 	note string
+
+	project string
+	tags    []string
+
+	add bool
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		jww.ERROR.Fatal(err)
+		fail(err)
 	}
 }
 
@@ -93,108 +106,267 @@ func Execute() {
 // runRoot performs the hole flow of the root command of timed.
 func runRoot(props RootCmdProps, repo db.Repo) error {
 
-	d, err := time.Parse("2006-01-02", props.date)
-	if err != nil && props.date != "" {
-		return err
-	}
-	if props.date == "" {
-		d = time.Now()
+	d := time.Now()
+	if props.date != "" {
+		var err error
+		if d, err = parseDate(props.date); err != nil {
+			return err
+		}
 	}
 
-	s, err := time.Parse("15:04", props.start)
-	if err != nil && props.start != "" {
+	s, err := parseClockTime(orNow(props.start), d)
+	if err != nil {
 		return err
 	}
 
-	e, err := time.Parse("15:04", props.end)
-	if err != nil && props.end != "" {
+	endDay := d
+	if props.endDate != "" {
+		if endDay, err = parseDate(props.endDate); err != nil {
+			return err
+		}
+	}
+	e, err := parseClockTime(orNow(props.end), endDay)
+	if err != nil {
 		return err
 	}
 
-	if wd := repo.LoadDay(&d); wd != nil {
-		s, e = mergeTimes(d, s, e)
+	if err = checkProject(props.project, repo); err != nil {
+		return err
+	}
+	session := db.Session{Start: s, Project: props.project, Tags: db.JoinTags(props.tags)}
 
-		// Update
-		if props.start != "" && s != wd.Start {
-			wd.Start = s
-		}
-		if props.end != "" && e != wd.End {
-			wd.End = e
+	wd, err := loadDay(repo, &d)
+	if err != nil {
+		return err
+	}
+	if wd != nil {
+		if props.add || len(wd.Sessions) == 0 {
+			// Append
+			if session.End, err = sessionEnd(props, s, e); err != nil {
+				return err
+			}
+			wd.Sessions = append(wd.Sessions, session)
+		} else {
+			// Update the latest session
+			last := &wd.Sessions[len(wd.Sessions)-1]
+			if props.start != "" && s != last.Start {
+				last.Start = s
+			}
+			if props.end != "" {
+				end, err := sessionEnd(props, last.Start, e)
+				if err != nil {
+					return err
+				}
+				if last.End == nil || *end != *last.End {
+					last.End = end
+				}
+			}
+			if props.project != "" {
+				last.Project = props.project
+			}
+			if len(props.tags) > 0 {
+				last.Tags = session.Tags
+			}
 		}
-		if props.brk > -1 && props.brk != wd.Brk {
-			wd.Brk = props.brk
+
+		if props.brk != nil {
+			wd.Brk = *props.brk
 		}
-		if props.note != wd.Note {
+		if props.note != "" {
 			wd.Note = props.note
 		}
 
-		repo.UpdateDay(*wd)
+		if err = validateDay(wd); err != nil {
+			return err
+		}
+		if err = repo.UpdateDay(*wd); err != nil {
+			return err
+		}
 	} else {
 		// Insert
 		b := 0
-		if props.start == "" {
-			s = time.Now()
+		if props.brk != nil {
+			b = *props.brk
 		}
-		if props.end == "" {
-			e = time.Now()
+		if session.End, err = sessionEnd(props, s, e); err != nil {
+			return err
 		}
-		if props.brk > -1 {
-			b = props.brk
+
+		newWd := db.WorkingDay{
+			Date:     time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Now().Location()),
+			Sessions: []db.Session{session},
+			Brk:      b,
+			Note:     props.note,
 		}
 
-		s, e = mergeTimes(d, s, e)
-		newWd := db.WorkingDay{Start: s, End: e, Brk: b, Note: props.note}
+		if err = validateDay(&newWd); err != nil {
+			return err
+		}
+		if err = repo.Insert(newWd); err != nil {
+			return err
+		}
+	}
 
-		repo.Insert(newWd)
+	return printReport(os.Stdout, repo)
+}
+
+// statusRecord is the machine readable form of the report of the root command
+type statusRecord struct {
+	RunningSince       *time.Time `json:"running_since"`
+	WorkedTodayMinutes *int       `json:"worked_today_minutes"`
+	TargetTodayMinutes *int       `json:"target_today_minutes"`
+	OvertimeMinutes    int        `json:"overtime_minutes"`
+}
+
+// printReport prints the report in the output format
+func printReport(output io.Writer, repo db.Repo) error {
+	f, err := format()
+	if err != nil {
+		return err
+	}
+	if f == "table" {
+		report, err := createReport(repo)
+		if err != nil {
+			return err
+		}
+		jww.FEEDBACK.Print(report)
+		return nil
 	}
 
-	report := createReport(repo)
-	jww.FEEDBACK.Print(report)
-	return nil
+	overtime, err := repo.Overtime()
+	if err != nil {
+		return err
+	}
+	status := statusRecord{OvertimeMinutes: overtime}
+
+	running, err := loadRunning(repo)
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		status.RunningSince = &running.Running().Start
+	}
+
+	t := time.Now()
+	today, err := loadDay(repo, &t)
+	if err != nil {
+		return err
+	}
+	if today != nil {
+		schedule, err := repo.Schedule()
+		if err != nil {
+			return err
+		}
+		worked, target := minutes(today.Worked()), minutes(schedule.TargetOfDay(today))
+		status.WorkedTodayMinutes, status.TargetTodayMinutes = &worked, &target
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Running since", "Worked today", "Target today", "Overtime"})
+	tw.AppendRow(table.Row{optional(status.RunningSince), optional(status.WorkedTodayMinutes),
+		optional(status.TargetTodayMinutes), status.OvertimeMinutes})
+
+	return render(output, tw, status)
 }
 
-func createReport(repo db.Repo) string {
+// optional dereferences the pointer of a table cell or returns an empty cell
+func optional(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *time.Time:
+		if v != nil {
+			return v.Format(time.RFC3339)
+		}
+	case *int:
+		if v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+func createReport(repo db.Repo) (string, error) {
 	b := strings.Builder{}
 
+	// Running?
+	wd, err := loadRunning(repo)
+	if err != nil {
+		return "", err
+	}
+	if wd != nil {
+		running := fmt.Sprintf("⏱  Running since %s\n", wd.Running().Start.Format("2006-01-02 15:04"))
+		b.WriteString(running)
+	}
+
 	// Worked today?
 	t := time.Now()
-	if wd := repo.LoadDay(&t); wd != nil {
-		diff := wd.End.Sub(wd.Start)
-		hrs := (diff.Minutes() - float64(wd.Brk)) / 60.0
-		workedToday := fmt.Sprintf("💪 Worked today %.2fhrs\n", hrs)
-		if _, err := b.WriteString(workedToday); err != nil {
-			jww.ERROR.Fatal(err)
+	if wd, err = loadDay(repo, &t); err != nil {
+		return "", err
+	}
+	if wd != nil {
+		schedule, err := repo.Schedule()
+		if err != nil {
+			return "", err
 		}
+		hrs := wd.Worked().Hours()
+		target := schedule.TargetOfDay(wd).Hours()
+		workedToday := fmt.Sprintf("💪 Worked today %.2fhrs of %.2fhrs\n", hrs, target)
+		b.WriteString(workedToday)
 	}
 
 	// Overtime in hours?
-	overtime := repo.Overtime()
+	overtime, err := repo.Overtime()
+	if err != nil {
+		return "", err
+	}
 	oInHour := float64(overtime) / 60
 	oStr := fmt.Sprintf("⏰  Total overtime %.2f hours", oInHour)
 	b.WriteString(oStr)
 
-	return b.String()
+	return b.String(), nil
 }
 
-func mergeTimes(day time.Time, start time.Time, end time.Time) (mStart time.Time, mEnd time.Time) {
-
-	year := day.Year()
-	month := day.Month()
-	dy := day.Day()
-
-	loc := day.Location()
+// sessionEnd returns the end of a session starting at start. Without an end date an end before the start lies on
+// the following day like the end of a night shift.
+func sessionEnd(props RootCmdProps, start time.Time, end time.Time) (*time.Time, error) {
+	if props.end != "" && props.endDate == "" {
+		end = when.EndAfter(start, end)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end %s is before start %s", end.Format("2006-01-02 15:04"), start.Format("2006-01-02 15:04"))
+	}
+	return &end, nil
+}
 
-	mStart = time.Date(year, month, dy, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), loc)
-	mEnd = time.Date(year, month, dy, end.Hour(), end.Minute(), end.Second(), end.Nanosecond(), loc)
+// changedBreak returns the break of the break flag or nil when the flag was not given. A given break is passed on
+// even when it is negative, so that the validation can reject it.
+func changedBreak(cmd *cobra.Command, brk *int) *int {
+	if cmd.Flags().Changed("break") {
+		return brk
+	}
+	return nil
+}
 
-	return
+// orNow returns "now" for an empty clock
+func orNow(clock string) string {
+	if clock == "" {
+		return "now"
+	}
+	return clock
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&rootCmdProps.date, "date", "d", "", `Takes the date that should be used. Format: "yyyy-mm-dd" -> E.g. 2019-03-28. (default: today)`)
-	rootCmd.Flags().StringVarP(&rootCmdProps.start, "start", "s", "", `Takes the start time. Format "hh:mm" -> E.g. "08:00". (default: now)`)
-	rootCmd.Flags().StringVarP(&rootCmdProps.end, "end", "e", "", `Parameter for end time. Format "hh:mm" -> E.g. "08:00". (default: now)`)
-
-	rootCmd.Flags().IntVarP(&rootCmdProps.brk, "break", "b", -1, "Takes the duration of the break in minutes. (default 0min)")
-	rootCmd.Flags().StringVarP(&rootCmdProps.note, "note", "n", "", "Takes a note and add it to an entry. Default: ''")
+	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Path of the config file. (default: $XDG_CONFIG_HOME/timed/config.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format: table, json, csv, markdown or html. (default: output of the config)")
+
+	rootCmd.Flags().StringVarP(&rootCmdProps.date, "date", "d", "", `Takes the date that should be used. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	rootCmd.Flags().StringVarP(&rootCmdProps.start, "start", "s", "", `Takes the start time. E.g. "08:00", "now-15m" or "+1h". (default: now)`)
+	rootCmd.Flags().StringVarP(&rootCmdProps.end, "end", "e", "", `Parameter for end time. E.g. "08:00", "now-15m" or "+1h". An end before the start lies on the following day. (default: now)`)
+	rootCmd.Flags().StringVar(&rootCmdProps.endDate, "end-date", "", `Takes the date of the end when it differs from the date. E.g. 2019-03-29 or tomorrow. (default: date)`)
+
+	rootCmd.Flags().IntVarP(rootCmdProps.brk, "break", "b", 0, "Takes the duration of the break in minutes. (default 0min)")
+	rootCmd.Flags().StringVarP(&rootCmdProps.note, "note", "n", "", "Takes a note and add it to an entry. An existing note is kept when omitted. Default: ''")
+	rootCmd.Flags().StringVarP(&rootCmdProps.project, "project", "p", "", "Takes the project the session was worked for.")
+	rootCmd.Flags().StringSliceVarP(&rootCmdProps.tags, "tag", "t", nil, "Takes tags of the session. Can be repeated or separated by comma.")
+	rootCmd.Flags().BoolVarP(&rootCmdProps.add, "add", "a", false, "Appends a new session to the day instead of updating the latest one.")
 }
@@ -8,48 +8,77 @@ import (
 
 func TestRunRoot(t *testing.T) {
 
-	repo := FakeRepo{make(map[string]db.WorkingDay)}
+	repo := newFakeRepo()
 
 	// insert
-	props := RootCmdProps{"2020-08-13", "10:00", "18:10", 30, "Note"}
+	props := RootCmdProps{date: "2020-08-13", start: "10:00", end: "18:10", brk: breakOf(30), note: "Note"}
 	err := runRoot(props, &repo)
 	if err != nil {
 		t.Fatal(err)
 	}
 	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
-	wd := repo.LoadDay(&day)
+	wd := repo.loadDay(day)
 	if wd == nil {
 		t.Fatal("Cmd did not create date")
 	}
-	if wd.Start.Year() != 2020 || wd.Start.Month() != 8 || wd.Start.Day() != 13 || wd.Start.Hour() != 10 ||
-		wd.Start.Minute() != 0 || wd.End.Hour() != 18 || wd.End.Minute() != 10 || wd.Brk != 30 || wd.Note != "Note" {
+	if wd.Start().Year() != 2020 || wd.Start().Month() != 8 || wd.Start().Day() != 13 || wd.Start().Hour() != 10 ||
+		wd.Start().Minute() != 0 || wd.End().Hour() != 18 || wd.End().Minute() != 10 || wd.Brk != 30 || wd.Note != "Note" {
 
 		t.Fatal("Cmd did not create correctly the working day")
 	}
 
 	// update
-	props = RootCmdProps{"2020-08-13", "09:25", "16:00", 40, "Note!"}
+	props = RootCmdProps{date: "2020-08-13", start: "09:25", end: "16:00", brk: breakOf(40), note: "Note!"}
 	err = runRoot(props, &repo)
 	if err != nil {
 		t.Fatal(err)
 	}
-	wd = repo.LoadDay(&day)
+	wd = repo.loadDay(day)
 	if wd == nil {
 		t.Fatal("Working day disappeared")
 	}
-	if wd.Start.Year() != 2020 || wd.Start.Month() != 8 || wd.Start.Day() != 13 || wd.Start.Hour() != 9 ||
-		wd.Start.Minute() != 25 || wd.End.Hour() != 16 || wd.End.Minute() != 00 || wd.Brk != 40 || wd.Note != "Note!" {
+	if wd.Start().Year() != 2020 || wd.Start().Month() != 8 || wd.Start().Day() != 13 || wd.Start().Hour() != 9 ||
+		wd.Start().Minute() != 25 || wd.End().Hour() != 16 || wd.End().Minute() != 00 || wd.Brk != 40 || wd.Note != "Note!" {
 
 		t.Fatal("Cmd did not update correctly the working day")
 	}
+	if len(wd.Sessions) != 1 {
+		t.Fatalf("Expected update to keep one session but got %d", len(wd.Sessions))
+	}
+
+	// append
+	props = RootCmdProps{date: "2020-08-13", start: "18:00", end: "20:00", brk: breakOf(40), note: "Note!", add: true}
+	err = runRoot(props, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd = repo.loadDay(day)
+	if len(wd.Sessions) != 2 {
+		t.Fatalf("Expected two sessions but got %d", len(wd.Sessions))
+	}
+	if wd.Sessions[0].End.Hour() != 16 || wd.Sessions[1].Start.Hour() != 18 || wd.End().Hour() != 20 {
+		t.Fatal("Cmd did not append the session correctly")
+	}
+	if wd.Worked() != 8*time.Hour+35*time.Minute-40*time.Minute {
+		t.Fatalf("Unexpected worked time %s", wd.Worked())
+	}
+
+	// update without note keeps the note
+	props = RootCmdProps{date: "2020-08-13", end: "21:00"}
+	if err = runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd = repo.loadDay(day); wd.Note != "Note!" || wd.End().Hour() != 21 {
+		t.Fatalf("Cmd did not keep the note: %s", wd)
+	}
 }
 
 func TestRunRootWithErrors(t *testing.T) {
 
-	repo := FakeRepo{make(map[string]db.WorkingDay)}
+	repo := newFakeRepo()
 
 	// Invalid date
-	props := RootCmdProps{"2020-08-32", "10:00", "18:10", 30, "Note"}
+	props := RootCmdProps{date: "2020-08-32", start: "10:00", end: "18:10", brk: breakOf(30), note: "Note"}
 	err := runRoot(props, &repo)
 	if err == nil {
 		t.Fatal("No error was returned hence an invalid date was passed")
@@ -71,11 +100,71 @@ func TestRunRootWithErrors(t *testing.T) {
 	}
 }
 
+func TestRunRootNightShift(t *testing.T) {
+
+	repo := newFakeRepo()
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+
+	// An end before the start lies on the following day
+	props := RootCmdProps{date: "2020-08-13", start: "22:00", end: "06:00", brk: breakOf(30)}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	wd := repo.loadDay(day)
+	if wd.End().Day() != 14 || wd.End().Hour() != 6 || wd.Worked() != 7*time.Hour+30*time.Minute {
+		t.Fatalf("Night shift was not stored correctly: %s", wd)
+	}
+	if repo.loadDay(day.AddDate(0, 0, 1)) != nil {
+		t.Fatal("Night shift created a second day")
+	}
+
+	// The end date can be passed explicitly
+	props = RootCmdProps{date: "2020-08-13", start: "20:00", end: "08:00", endDate: "2020-08-14"}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+	wd = repo.loadDay(day)
+	if len(wd.Sessions) != 1 || wd.End().Day() != 14 || wd.Worked() != 11*time.Hour+30*time.Minute {
+		t.Fatalf("Explicit end date was not applied: %s", wd)
+	}
+
+	props.endDate = "2020-08-15"
+	if err := runRoot(props, &repo); err == nil || exitCode(err) != exitRejected {
+		t.Fatalf("Accepted a session longer than a day: %v", err)
+	}
+
+	props.endDate = "2020-08-12"
+	if err := runRoot(props, &repo); err == nil {
+		t.Fatal("Accepted an end date before the start")
+	}
+}
+
+func TestRunRootNaturalInput(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	props := RootCmdProps{date: "yesterday", start: "00:00", end: "now-15m"}
+	if err := runRoot(props, &repo); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+	wd := repo.loadDay(yesterday)
+	if wd == nil {
+		t.Fatal("Cmd did not create yesterday")
+	}
+	end := now.Add(-15 * time.Minute)
+	if wd.Start().Hour() != 0 || wd.End().Day() != yesterday.Day() || wd.End().Hour() != end.Hour() || wd.End().Minute() != end.Minute() {
+		t.Fatalf("Cmd did not resolve the natural input correctly: %v - %v", wd.Start(), wd.End())
+	}
+}
+
 func TestCreateReport(t *testing.T) {
-	repo := FakeRepo{make(map[string]db.WorkingDay)}
+	repo := newFakeRepo()
 
 	// Not worked today
-	report := createReport(&repo)
+	report, _ := createReport(&repo)
 	if report != "⏰  Total overtime 2.05 hours" {
 		t.Fatalf("Did not create report correctly overtime: Got '%s'", report)
 	}
@@ -84,10 +173,20 @@ func TestCreateReport(t *testing.T) {
 	tNow := time.Now()
 	start := time.Date(tNow.Year(), tNow.Month(), tNow.Day(), 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(tNow.Year(), tNow.Month(), tNow.Day(), 16, 20, 00, 000, time.Now().Location())
-	wd := db.WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
+	wd := newWorkingDay(start, end, 30, "With space")
 	repo.Insert(wd)
-	report = createReport(&repo)
-	if report != "💪 Worked today 8.00hrs\n⏰  Total overtime 2.05 hours" {
+	report, _ = createReport(&repo)
+	if report != "💪 Worked today 8.00hrs of 8.00hrs\n⏰  Total overtime 2.05 hours" {
 		t.Fatalf("Did not create report correctly overtime or worked hours today: Got '%s'", report)
 	}
+
+	// Worked today in two sessions
+	start = time.Date(tNow.Year(), tNow.Month(), tNow.Day(), 17, 00, 00, 000, time.Now().Location())
+	end = time.Date(tNow.Year(), tNow.Month(), tNow.Day(), 18, 30, 00, 000, time.Now().Location())
+	wd.Sessions = append(wd.Sessions, db.Session{Start: start, End: &end})
+	repo.UpdateDay(wd)
+	report, _ = createReport(&repo)
+	if report != "💪 Worked today 9.50hrs of 8.00hrs\n⏰  Total overtime 2.05 hours" {
+		t.Fatalf("Did not sum up the sessions of today: Got '%s'", report)
+	}
 }
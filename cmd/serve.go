@@ -0,0 +1,122 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/corka149/timed/api"
+	"github.com/corka149/timed/db"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	serveCmdProps = ServeCmdProps{}
+
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serves the working days as REST API",
+		Long: `Serve exposes the working days, the overtime and clocking in and out as REST API with JSON bodies.
+Requests must carry the api_token of the configuration as bearer token when one is set. GET /openapi.json
+describes all endpoints.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runServe(serveCmdProps, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// ServeCmdProps represents all local properties of the serve command
+type ServeCmdProps struct {
+	listen string
+	token  string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// runServe handles requests until the server fails
+func runServe(props ServeCmdProps, repo db.Repo) error {
+	handler, err := serveHandler(props, repo)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", props.listen)
+	if err != nil {
+		return err
+	}
+	jww.FEEDBACK.Printf("Serving on http://%s\n", listener.Addr())
+
+	return http.Serve(listener, handler)
+}
+
+// serveHandler creates the API with the token of the flag or the configuration
+func serveHandler(props ServeCmdProps, repo db.Repo) (http.Handler, error) {
+	rules, err := validationRules()
+	if err != nil {
+		return nil, err
+	}
+
+	token := props.token
+	if token == "" {
+		token = cfg.APIToken
+	}
+	if token == "" {
+		jww.WARN.Println("No api_token is configured - every client which can reach the server can change the data")
+	}
+
+	return api.NewServer(repo, token, rules, cfg.ListDays, serveBackup), nil
+}
+
+// serveBackup takes a backup before the API deletes a working day
+func serveBackup() error {
+	b, err := backupBeforeChange()
+	if b != nil {
+		jww.INFO.Printf("Saved backup %s\n", b.ID)
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&serveCmdProps.listen, "listen", "l", "127.0.0.1:8421", "Address the server listens on.")
+	serveCmd.Flags().StringVar(&serveCmdProps.token, "token", "", "Bearer token required by the API. (default: api_token of the config)")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHandlerToken(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	old := cfg
+	cfg.APIToken = "configured"
+	defer func() { cfg = old }()
+
+	for _, tt := range []struct {
+		props  ServeCmdProps
+		auth   string
+		status int
+	}{
+		{ServeCmdProps{}, "", http.StatusUnauthorized},
+		{ServeCmdProps{}, "Bearer configured", http.StatusOK},
+		{ServeCmdProps{token: "flag"}, "Bearer configured", http.StatusUnauthorized},
+		{ServeCmdProps{token: "flag"}, "Bearer flag", http.StatusOK},
+	} {
+		handler, err := serveHandler(tt.props, &repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/overtime", nil)
+		req.Header.Set("Authorization", tt.auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("Token %q with %q: expected %d but got %d", tt.props.token, tt.auth, tt.status, rec.Code)
+		}
+	}
+}
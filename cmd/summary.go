@@ -0,0 +1,157 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+const noGroup = "(none)"
+
+var (
+	summaryCmdProps = SummaryCmdProps{}
+
+	summaryCmd = &cobra.Command{
+		Use:   "summary",
+		Short: "Sums up hours per project or tag",
+		Long: `Summary sums up the hours of all sessions in a given range grouped by project or tag.
+Breaks belong to a day and not to a session, so they are not subtracted. A session with several tags
+counts for each of its tags. By default it looks as many days back as configured by list_days (default: 30)`,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runSummary(summaryCmdProps, os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// SummaryCmdProps represents all local properties of the summary command
+type SummaryCmdProps struct {
+	startDate string
+	endDate   string
+
+	by      string
+	project string
+	tag     string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// summaryRecord is the machine readable form of a summary
+type summaryRecord struct {
+	GroupBy      string         `json:"group_by"`
+	Groups       []summaryGroup `json:"groups"`
+	TotalMinutes int            `json:"total_minutes"`
+}
+
+// summaryGroup contains the hours of a project or tag
+type summaryGroup struct {
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+	Minutes  int    `json:"minutes"`
+}
+
+func runSummary(props SummaryCmdProps, output io.Writer, repo db.Repo) error {
+	if props.by != "project" && props.by != "tag" {
+		return fmt.Errorf("cannot group by '%s' - use project or tag", props.by)
+	}
+
+	workingDays, err := listRange(props.startDate, props.endDate, repo)
+	if err != nil {
+		return err
+	}
+
+	hours := make(map[string]time.Duration)
+	sessions := make(map[string]int)
+	var total time.Duration
+
+	for _, wd := range workingDays {
+		for _, s := range wd.Sessions {
+			if !s.Matches(props.project, props.tag) {
+				continue
+			}
+
+			groups := []string{s.Project}
+			if props.by == "tag" {
+				groups = s.TagList()
+			}
+			if len(groups) == 0 || groups[0] == "" {
+				groups = []string{noGroup}
+			}
+
+			for _, g := range groups {
+				hours[g] += s.Duration()
+				sessions[g]++
+			}
+			total += s.Duration()
+		}
+	}
+
+	groups := make([]string, 0, len(hours))
+	for g := range hours {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	summary := summaryRecord{GroupBy: props.by, Groups: make([]summaryGroup, 0, len(groups)), TotalMinutes: minutes(total)}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{props.by, "Sessions", "Hours"})
+	for _, g := range groups {
+		t.AppendRow(table.Row{g, sessions[g], fmt.Sprintf("%.2f", hours[g].Hours())})
+		summary.Groups = append(summary.Groups, summaryGroup{Name: g, Sessions: sessions[g], Minutes: minutes(hours[g])})
+	}
+	t.AppendFooter(table.Row{"Total", "", fmt.Sprintf("%.2f", total.Hours())})
+
+	return render(output, t, summary)
+}
+
+func init() {
+	rootCmd.AddCommand(summaryCmd)
+	summaryCmd.Flags().StringVarP(&summaryCmdProps.startDate, "start", "s", "", `Start date of selection. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	summaryCmd.Flags().StringVarP(&summaryCmdProps.endDate, "end", "e", "", `End date of selection. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	summaryCmd.Flags().StringVarP(&summaryCmdProps.by, "by", "b", "project", "Groups the hours by project or tag.")
+	summaryCmd.Flags().StringVarP(&summaryCmdProps.project, "project", "p", "", "Sums up only sessions of the project.")
+	summaryCmd.Flags().StringVarP(&summaryCmdProps.tag, "tag", "t", "", "Sums up only sessions having the tag.")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunSummary(t *testing.T) {
+
+	repo := newFakeRepo()
+	for _, p := range []string{"acme", "globex"} {
+		if err := runProjectAdd(p, &repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions := []RootCmdProps{
+		{date: "2020-08-13", start: "08:00", end: "10:00", project: "acme", tags: []string{"bug"}},
+		{date: "2020-08-13", start: "10:00", end: "11:30", brk: breakOf(30), project: "globex", tags: []string{"bug", "call"}, add: true},
+		{date: "2020-08-14", start: "08:00", end: "09:00", add: true},
+	}
+	for _, props := range sessions {
+		if err := runRoot(props, &repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testOut := strings.Builder{}
+	err := runSummary(SummaryCmdProps{startDate: "2020-08-01", endDate: "2020-08-31", by: "project"}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{"|acme|1|2.00|", "|globex|1|1.50|", "|(none)|1|1.00|", "|TOTAL||4.50|"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected '%s' in %s", expected, testOut.String())
+		}
+	}
+
+	testOut.Reset()
+	err = runSummary(SummaryCmdProps{startDate: "2020-08-01", endDate: "2020-08-31", by: "tag", tag: "bug"}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = strings.ReplaceAll(testOut.String(), " ", "")
+	for _, expected := range []string{"|bug|2|3.50|", "|call|1|1.50|", "|TOTAL||3.50|"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected '%s' in %s", expected, testOut.String())
+		}
+	}
+
+	if err = runSummary(SummaryCmdProps{by: "client"}, &testOut, &repo); err == nil {
+		t.Fatal("Accepted unknown grouping")
+	}
+
+	// List filters by project
+	testOut.Reset()
+	err = runList(ListCmdProps{startDate: "2020-08-01", endDate: "2020-08-31", project: "globex"}, &testOut, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(testOut.String(), "2020-08-13") || strings.Contains(testOut.String(), "2020-08-14") {
+		t.Fatalf("List did not filter by project: %s", testOut.String())
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	targetSetCmdProps = TargetSetCmdProps{}
+
+	weekdays = map[string]time.Weekday{
+		"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+		"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+	}
+
+	targetCmd = &cobra.Command{
+		Use:   "target",
+		Short: "Manages the daily target hours",
+		Long:  "Target manages how many hours should be worked per weekday. A target is valid from its date until the next target.",
+	}
+
+	targetSetCmd = &cobra.Command{
+		Use:   "set",
+		Short: "Sets the target hours from a date on",
+		Long: `Set defines the target hours per weekday from a date on. Weekdays without target hours are free.
+A weekday can get its own hours -> E.g. --weekdays mon,tue,wed,thu=6,fri=4`,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runTargetSet(targetSetCmdProps, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	targetListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists the target hours",
+		Long:  "List shows all target hours with the date from which they are valid",
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runTargetList(os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// TargetSetCmdProps represents all local properties of the target set command
+type TargetSetCmdProps struct {
+	from     string
+	hours    float64
+	weekdays []string
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// targetRecord is the machine readable form of a target
+type targetRecord struct {
+	ValidFrom string         `json:"valid_from"`
+	Minutes   map[string]int `json:"minutes"`
+}
+
+// runTargetSet stores the target hours defined by the props.
+func runTargetSet(props TargetSetCmdProps, repo db.Repo) error {
+	from, err := parseDateOrDefault(props.from)
+	if err != nil {
+		return err
+	}
+
+	target := db.Target{ValidFrom: *from}
+	for _, wd := range props.weekdays {
+		name, hours := wd, props.hours
+		if i := strings.Index(wd, "="); i > -1 {
+			name = wd[:i]
+			if hours, err = strconv.ParseFloat(wd[i+1:], 64); err != nil {
+				return fmt.Errorf("invalid hours for weekday '%s': %w", name, err)
+			}
+		}
+
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return fmt.Errorf("unknown weekday '%s'", name)
+		}
+		if hours < 0 || hours > 24 {
+			return fmt.Errorf("target hours of '%s' must be between 0 and 24", name)
+		}
+		target.Set(weekday, time.Duration(hours*float64(time.Hour)))
+	}
+
+	if err := repo.SetTarget(target); err != nil {
+		return err
+	}
+	jww.FEEDBACK.Println(target.String())
+	return nil
+}
+
+// runTargetList renders all targets as table.
+func runTargetList(output io.Writer, repo db.Repo) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Valid from", "Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"})
+
+	schedule, err := repo.Schedule()
+	if err != nil {
+		return err
+	}
+
+	records := make([]targetRecord, 0)
+	for _, target := range schedule.Targets {
+		row := table.Row{target.ValidFrom.Format(dateLayout())}
+		record := targetRecord{ValidFrom: target.ValidFrom.Format("2006-01-02"), Minutes: make(map[string]int)}
+
+		for _, wd := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+			row = append(row, fmt.Sprintf("%.2f", target.Of(wd).Hours()))
+			record.Minutes[strings.ToLower(wd.String())] = int(target.Of(wd).Minutes())
+		}
+
+		t.AppendRow(row)
+		records = append(records, record)
+	}
+
+	return render(output, t, records)
+}
+
+func init() {
+	rootCmd.AddCommand(targetCmd)
+	targetCmd.AddCommand(targetSetCmd)
+	targetCmd.AddCommand(targetListCmd)
+
+	targetSetCmd.Flags().StringVarP(&targetSetCmdProps.from, "from", "f", "", `Date from which the target is valid. E.g. 2019-03-28, yesterday, "last friday" or -2d. (default: today)`)
+	targetSetCmd.Flags().Float64VarP(&targetSetCmdProps.hours, "hours", "H", db.DefaultTarget.Hours(), "Target hours of each weekday without own hours.")
+	targetSetCmd.Flags().StringSliceVarP(&targetSetCmdProps.weekdays, "weekdays", "w", []string{"mon", "tue", "wed", "thu", "fri"}, "Weekdays which have to be worked. Format: weekday[=hours]")
+}
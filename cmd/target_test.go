@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTargetSet(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	props := TargetSetCmdProps{from: "2021-01-01", hours: 6, weekdays: []string{"mon", "tue", "wed", "thu=7.5"}}
+	err := runTargetSet(props, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedule, _ := repo.Schedule()
+	monday := time.Date(2021, 1, 4, 0, 0, 0, 0, time.Now().Location())
+	if target := schedule.TargetOf(monday); target != 6*time.Hour {
+		t.Fatalf("Expected 6h on monday but got %s", target)
+	}
+	if target := schedule.TargetOf(monday.AddDate(0, 0, 3)); target != 7*time.Hour+30*time.Minute {
+		t.Fatalf("Expected 7.5h on thursday but got %s", target)
+	}
+	if target := schedule.TargetOf(monday.AddDate(0, 0, 4)); target != 0 {
+		t.Fatalf("Expected no target on friday but got %s", target)
+	}
+	if target := schedule.TargetOf(monday.AddDate(0, 0, -7)); target != 8*time.Hour {
+		t.Fatalf("Expected default target before the first target but got %s", target)
+	}
+
+	testOut := strings.Builder{}
+	if err = runTargetList(&testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(testOut.String(), "2021-01-01") || !strings.Contains(testOut.String(), "7.50") {
+		t.Fatalf("Did not list target: %s", testOut.String())
+	}
+}
+
+func TestRunTargetSetWithErrors(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	props := TargetSetCmdProps{from: "2021-01-01", hours: 6, weekdays: []string{"monday"}}
+	if err := runTargetSet(props, &repo); err == nil {
+		t.Fatal("Accepted unknown weekday")
+	}
+
+	props.weekdays = []string{"mon=x"}
+	if err := runTargetSet(props, &repo); err == nil {
+		t.Fatal("Accepted invalid hours")
+	}
+
+	props.weekdays = []string{"mon=25"}
+	if err := runTargetSet(props, &repo); err == nil {
+		t.Fatal("Accepted more than 24 hours")
+	}
+}
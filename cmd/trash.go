@@ -0,0 +1,183 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	trashPurgeCmdProps = TrashPurgeCmdProps{}
+
+	trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "Manages deleted working days",
+		Long:  "Trash manages the working days which were deleted. They can be restored until they are purged.",
+	}
+
+	trashListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists deleted working days",
+		Long:  "List shows all deleted working days with the ID which is needed to restore them.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runTrashList(os.Stdout, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	trashRestoreCmd = &cobra.Command{
+		Use:   "restore ID",
+		Short: "Restores the deleted working day ID",
+		Long:  "Restore brings a deleted working day back. It is refused when another working day exists on the same date.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			if err := runTrashRestore(args[0], repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+
+	trashPurgeCmd = &cobra.Command{
+		Use:   "purge",
+		Short: "Removes deleted working days permanently",
+		Long:  "Purge removes the working days which were deleted more than --older-than days ago permanently. The audit trail keeps them.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			protect()
+			repo := openRepo()
+
+			if err := runTrashPurge(trashPurgeCmdProps, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// TrashPurgeCmdProps represents all local properties of the trash purge command
+type TrashPurgeCmdProps struct {
+	olderThan int
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// trashRecord is the machine readable form of a deleted working day
+type trashRecord struct {
+	ID        uint      `json:"id"`
+	Date      string    `json:"date"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Day       string    `json:"day"`
+}
+
+// runTrashList renders all deleted working days
+func runTrashList(output io.Writer, repo db.Repo) error {
+	trash, err := repo.Trash()
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"ID", "Date", "Deleted at", "Day"})
+
+	records := make([]trashRecord, 0, len(trash))
+	for i := range trash {
+		wd := &trash[i]
+		r := trashRecord{ID: wd.ID, Date: wd.Date.Format("2006-01-02"), DeletedAt: wd.DeletedAt.Time, Day: describeDay(wd)}
+		t.AppendRow(table.Row{r.ID, wd.Date.Format(dateLayout()), r.DeletedAt.Local().Format("2006-01-02 15:04"), r.Day})
+		records = append(records, r)
+	}
+
+	return render(output, t, records)
+}
+
+// runTrashRestore brings the deleted working day with the id back
+func runTrashRestore(id string, repo db.Repo) error {
+	n, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return fmt.Errorf("invalid ID '%s' - see 'timed trash list'", id)
+	}
+
+	wd, err := repo.RestoreDeleted(uint(n))
+	if errors.Is(err, db.ErrNotFound) {
+		return notFound("no deleted working day with ID %d", n)
+	}
+	if errors.Is(err, db.ErrConflict) {
+		return conflict("a working day exists on the date of the deleted working day %d - delete it first", n)
+	}
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Restored working day of %s\n", wd.Date.Format(dateLayout()))
+	return nil
+}
+
+// runTrashPurge removes the working days which were deleted before the configured number of days permanently
+func runTrashPurge(props TrashPurgeCmdProps, repo db.Repo) error {
+	if props.olderThan < 0 {
+		return errors.New("--older-than must not be negative")
+	}
+
+	purged, err := repo.Purge(time.Now().AddDate(0, 0, -props.olderThan))
+	if err != nil {
+		return err
+	}
+
+	jww.FEEDBACK.Printf("Purged %d deleted working days\n", purged)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+
+	trashPurgeCmd.Flags().IntVar(&trashPurgeCmdProps.olderThan, "older-than", 30, "Purges only working days which were deleted more than this number of days ago. 0 purges all.")
+}
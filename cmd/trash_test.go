@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTrash(t *testing.T) {
+
+	repo := newFakeRepo()
+
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, time.Now().Location())
+	repo.Insert(newWorkingDay(start, start.Add(4*time.Hour), 0, "trashed"))
+	if err := runDelete([]string{"2020-08-13"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+
+	testOut := strings.Builder{}
+	if err := runTrashList(&testOut, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(testOut.String(), "note 'trashed'") {
+		t.Fatalf("Deleted day is not listed: %s", testOut.String())
+	}
+	trash, _ := repo.Trash()
+	id := trash[0].ID
+
+	repo.Insert(newWorkingDay(start, start.Add(time.Hour), 0, "new"))
+	if err := runTrashRestore(itoa(id), &repo); err == nil || exitCode(err) != exitConflict {
+		t.Fatalf("Expected conflict but got %v", err)
+	}
+	if err := runDelete([]string{"2020-08-13"}, DeleteCmdProps{yes: true}, nil, ioutil.Discard, &repo, noBackup); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runTrashRestore(itoa(id), &repo); err != nil {
+		t.Fatal(err)
+	}
+	if wd := repo.loadDay(start); wd == nil || wd.Note != "trashed" {
+		t.Fatalf("Day was not restored: %v", wd)
+	}
+	if err := runTrashRestore("abc", &repo); err == nil {
+		t.Fatal("Accepted invalid ID")
+	}
+	if err := runTrashRestore(itoa(id), &repo); err == nil || exitCode(err) != exitNotFound {
+		t.Fatalf("Expected not found but got %v", err)
+	}
+
+	if err := runTrashPurge(TrashPurgeCmdProps{olderThan: 1}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if trash, _ = repo.Trash(); len(trash) != 1 {
+		t.Fatalf("Purged recently deleted days: %+v", trash)
+	}
+	if err := runTrashPurge(TrashPurgeCmdProps{olderThan: 0}, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if trash, _ = repo.Trash(); len(trash) != 0 {
+		t.Fatalf("Trash is not empty after purge: %+v", trash)
+	}
+	if err := runTrashPurge(TrashPurgeCmdProps{olderThan: -1}, &repo); err == nil {
+		t.Fatal("Accepted negative days")
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,80 @@
+/*
+Package cmd contains all commands that belongs to the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/tui"
+	"github.com/gdamore/tcell/v2"
+	"github.com/spf13/cobra"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	tuiCmd = &cobra.Command{
+		Use:   "tui",
+		Short: "Opens the working days in a terminal UI",
+		Long: `Tui shows a month of working days as calendar with the details of the selected day, a running timer and the
+overtime balance. Start, end, break and note of a day can be edited by keyboard. Changes are validated and stored
+right away.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := openRepo()
+
+			screen, err := tcell.NewScreen()
+			if err != nil {
+				fail(err)
+			}
+			if err := runTui(screen, repo); err != nil {
+				fail(err)
+			}
+		},
+	}
+)
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// runTui shows the terminal UI on the screen until the user quits
+func runTui(screen tcell.Screen, repo db.Repo) error {
+	rules, err := validationRules()
+	if err != nil {
+		return err
+	}
+
+	if err = screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	return tui.New(repo, rules).Run(screen)
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -2,16 +2,178 @@
 package cmd
 
 import (
-	"github.com/mitchellh/go-homedir"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/corka149/timed/config"
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
 	jww "github.com/spf13/jwalterweatherman"
-	"path/filepath"
 )
 
-// DbPath returns the path to the database
+var (
+	// cfgFile is the path of the config file passed by flag
+	cfgFile string
+
+	// cfg is the loaded configuration
+	cfg = config.Default()
+)
+
+// initConfig loads the config file and applies the environment overrides
+func initConfig() {
+	path, err := ConfigPath()
+	if err != nil {
+		fail(err)
+	}
+
+	if cfg, err = config.Load(path); err != nil {
+		fail(err)
+	}
+	if err = cfg.ApplyEnv(); err != nil {
+		fail(err)
+	}
+
+	// Day boundaries and entered times follow the configured zone
+	loc, err := cfg.Location()
+	if err != nil {
+		fail(err)
+	}
+	time.Local = loc
+}
+
+// ConfigPath returns the path to the config file
+func ConfigPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	return config.Path()
+}
+
+// DbPath returns the path or data source name of the database
 func DbPath() string {
-	home, err := homedir.Dir()
+	path, err := cfg.Database()
+	if err != nil {
+		fail(err)
+	}
+	return path
+}
+
+// openRepo creates the repo as configured. A SQLite database is backed up when the latest backup is too old.
+func openRepo() db.Repo {
+	repo, err := db.Open(DbPath(), cfg.Target())
+	if err != nil {
+		fail(err)
+	}
+
+	if s, err := backupSettingsOf(); err == nil {
+		if _, err = scheduledBackup(s, time.Now()); err != nil {
+			jww.WARN.Printf("Could not back up the database: %s\n", err)
+		}
+	}
+	return repo
+}
+
+// loadDay loads the working day of the date. It is nil when there is no working day on that date.
+func loadDay(repo db.Repo, d *time.Time) (*db.WorkingDay, error) {
+	wd, err := repo.LoadDay(d)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	return wd, err
+}
+
+// loadRunning loads the working day with the running session. It is nil when no session is running.
+func loadRunning(repo db.Repo) (*db.WorkingDay, error) {
+	wd, err := repo.LoadRunning()
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	return wd, err
+}
+
+// Exit codes of timed
+const (
+	exitFailure  = 1
+	exitNotFound = 3
+	exitConflict = 4
+	exitInvalid  = 5
+	exitRejected = 6
+)
+
+// exitCode maps an error to the exit code of timed
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, db.ErrConflict), errors.Is(err, db.ErrNotEmpty):
+		return exitConflict
+	case errors.Is(err, db.ErrInvalidArchive), errors.Is(err, db.ErrInvalidBackup):
+		return exitInvalid
+	case errors.Is(err, db.ErrInvalid):
+		return exitRejected
+	default:
+		return exitFailure
+	}
+}
+
+// kindError is a message for the user which keeps the kind of error for the exit code
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (e kindError) Error() string {
+	return e.message
+}
+
+func (e kindError) Unwrap() error {
+	return e.kind
+}
+
+// notFound creates an error with the message which is reported as db.ErrNotFound
+func notFound(format string, args ...interface{}) error {
+	return kindError{message: fmt.Sprintf(format, args...), kind: db.ErrNotFound}
+}
+
+// conflict creates an error with the message which is reported as db.ErrConflict
+func conflict(format string, args ...interface{}) error {
+	return kindError{message: fmt.Sprintf(format, args...), kind: db.ErrConflict}
+}
+
+// rejected creates an error with the message which is reported as db.ErrInvalid
+func rejected(format string, args ...interface{}) error {
+	return kindError{message: fmt.Sprintf(format, args...), kind: db.ErrInvalid}
+}
+
+// fail reports the error and exits with the exit code matching the error
+func fail(err error) {
+	jww.ERROR.Println(err)
+	os.Exit(exitCode(err))
+}
+
+// parseDate parses a date like "2019-03-28", "28.03.2019", "yesterday", "last friday" or one in the format of the
+// configured locale
+func parseDate(date string) (time.Time, error) {
+	return when.Date(date, time.Now(), cfg.DateLayout())
+}
+
+// checkProject ensures that a project exists and is not archived. An empty name means no project.
+func checkProject(name string, repo db.Repo) error {
+	if name == "" {
+		return nil
+	}
+
+	p, err := repo.LoadProject(name)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("unknown project '%s' - add it with 'timed project add %s'", name, name)
+	}
 	if err != nil {
-		jww.ERROR.Fatal(err)
+		return err
+	}
+	if p.Archived {
+		return fmt.Errorf("project '%s' is archived", name)
 	}
-	return filepath.Join(home, ".timed.db")
+	return nil
 }
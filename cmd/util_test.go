@@ -1,51 +1,337 @@
 package cmd
 
 import (
+	"encoding/json"
 	"github.com/corka149/timed/db"
+	"gorm.io/gorm"
+	"sort"
 	"time"
 )
 
 type FakeRepo struct {
-	data map[string]db.WorkingDay
+	data     map[string]db.WorkingDay
+	targets  []db.Target
+	projects map[string]db.Project
+	changes  []db.Change
+	audit    []db.AuditEntry
+	trash    []db.WorkingDay
 }
 
-func (r *FakeRepo) LoadDay(d *time.Time) *db.WorkingDay {
+func newFakeRepo() FakeRepo {
+	return FakeRepo{data: make(map[string]db.WorkingDay), projects: make(map[string]db.Project)}
+}
+
+func (r *FakeRepo) LoadDay(d *time.Time) (*db.WorkingDay, error) {
 	date := d.Format("2006-01-02")
 	wd, ok := r.data[date]
 	if ok {
-		return &wd
+		// Sessions are copied like they would be loaded from a database
+		wd.Sessions = append([]db.Session(nil), wd.Sessions...)
+		return &wd, nil
 	} else {
-		return nil
+		return nil, db.ErrNotFound
 	}
 }
 
-func (r *FakeRepo) UpdateDay(wd db.WorkingDay) {
-	date := wd.Start.Format("2006-01-02")
+func (r *FakeRepo) UpdateDay(wd db.WorkingDay) error {
+	date := wd.Date.Format("2006-01-02")
+	before, ok := r.data[date]
+	if !ok {
+		return db.ErrNotFound
+	}
 	r.data[date] = wd
+	r.journal(db.Updated, &before, &wd)
+	return nil
 }
 
-func (r *FakeRepo) Insert(wd db.WorkingDay) {
-	date := wd.Start.Format("2006-01-02")
+func (r *FakeRepo) Insert(wd db.WorkingDay) error {
+	date := wd.Date.Format("2006-01-02")
+	if _, ok := r.data[date]; ok {
+		return db.ErrConflict
+	}
 	r.data[date] = wd
+	r.journal(db.Inserted, nil, &wd)
+	return nil
 }
 
-func (r FakeRepo) Delete(wd db.WorkingDay) {
-	date := wd.Start.Format("2006-01-02")
+func (r *FakeRepo) Delete(wd db.WorkingDay) error {
+	date := wd.Date.Format("2006-01-02")
+	before, ok := r.data[date]
+	if !ok {
+		return db.ErrNotFound
+	}
 	delete(r.data, date)
+	r.journal(db.Deleted, &before, nil)
+
+	if before.ID == 0 {
+		before.ID = uint(1000 + len(r.trash))
+	}
+	before.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.trash = append(r.trash, before)
+	return nil
+}
+
+func (r *FakeRepo) Trash() ([]db.WorkingDay, error) {
+	trash := make([]db.WorkingDay, 0, len(r.trash))
+	for i := len(r.trash) - 1; i >= 0; i-- {
+		trash = append(trash, r.trash[i])
+	}
+	return trash, nil
+}
+
+func (r *FakeRepo) RestoreDeleted(id uint) (*db.WorkingDay, error) {
+	for i, wd := range r.trash {
+		if wd.ID != id {
+			continue
+		}
+
+		wd.DeletedAt = gorm.DeletedAt{}
+		if err := r.Insert(wd); err != nil {
+			return nil, err
+		}
+		r.trash = append(r.trash[:i], r.trash[i+1:]...)
+		return &wd, nil
+	}
+	return nil, db.ErrNotFound
+}
+
+func (r *FakeRepo) Purge(before time.Time) (int, error) {
+	kept := make([]db.WorkingDay, 0, len(r.trash))
+	for _, wd := range r.trash {
+		if !wd.DeletedAt.Time.Before(before) {
+			kept = append(kept, wd)
+		}
+	}
+
+	purged := len(r.trash) - len(kept)
+	r.trash = kept
+	return purged, nil
+}
+
+func (r *FakeRepo) LoadRunning() (*db.WorkingDay, error) {
+	for _, wd := range r.data {
+		if wd.Running() != nil {
+			return &wd, nil
+		}
+	}
+	return nil, db.ErrNotFound
 }
 
-func (r FakeRepo) Overtime() int {
-	return 123
+func (r FakeRepo) Overtime() (int, error) {
+	return 123, nil
 }
 
 func (r FakeRepo) ListRange(start *time.Time, end *time.Time) ([]db.WorkingDay, error) {
 	inRange := make([]db.WorkingDay, 0)
 
 	for _, wd := range r.data {
-		if wd.Start.After(*start) && wd.End.Before(*end) {
+		if !wd.Date.Before(*start) && !wd.Date.After(*end) {
 			inRange = append(inRange, wd)
 		}
 	}
 
 	return inRange, nil
 }
+
+func (r *FakeRepo) Schedule() (db.Schedule, error) {
+	return db.NewSchedule(r.targets, db.DefaultTarget), nil
+}
+
+func (r *FakeRepo) SetTarget(t db.Target) error {
+	r.targets = append(r.targets, t)
+	return nil
+}
+
+func (r *FakeRepo) LoadProject(name string) (*db.Project, error) {
+	p, ok := r.projects[name]
+	if ok {
+		return &p, nil
+	}
+	return nil, db.ErrNotFound
+}
+
+func (r *FakeRepo) SaveProject(p db.Project) error {
+	if p.ID == 0 {
+		p.ID = uint(len(r.projects) + 1)
+	}
+	r.projects[p.Name] = p
+	return nil
+}
+
+func (r *FakeRepo) Projects() ([]db.Project, error) {
+	projects := make([]db.Project, 0, len(r.projects))
+	for _, p := range r.projects {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects, nil
+}
+
+func (r *FakeRepo) Transaction(fc func(tx db.Repo) error) error {
+	data := make(map[string]db.WorkingDay, len(r.data))
+	for k, v := range r.data {
+		data[k] = v
+	}
+	projects := make(map[string]db.Project, len(r.projects))
+	for k, v := range r.projects {
+		projects[k] = v
+	}
+
+	changes := append([]db.Change{}, r.changes...)
+
+	err := fc(r)
+	if err != nil {
+		r.data, r.projects, r.changes = data, projects, changes
+	}
+	return err
+}
+
+func (r *FakeRepo) Dump() (db.ArchiveTables, error) {
+	dates := make([]string, 0, len(r.data))
+	for date := range r.data {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	projects, _ := r.Projects()
+	tables := db.ArchiveTables{Targets: r.targets, Projects: projects}
+	for i, date := range dates {
+		wd := r.data[date]
+		wd.ID = uint(i + 1)
+		for _, s := range wd.Sessions {
+			s.ID = uint(len(tables.Sessions) + 1)
+			s.WorkingDayID = wd.ID
+			tables.Sessions = append(tables.Sessions, s)
+		}
+		wd.Sessions = nil
+		tables.WorkingDays = append(tables.WorkingDays, wd)
+	}
+	return tables, nil
+}
+
+func (r *FakeRepo) Restore(tables db.ArchiveTables) error {
+	if len(r.data) > 0 || len(r.targets) > 0 || len(r.projects) > 0 {
+		return db.ErrNotEmpty
+	}
+
+	for _, wd := range tables.WorkingDays {
+		for _, s := range tables.Sessions {
+			if s.WorkingDayID == wd.ID {
+				wd.Sessions = append(wd.Sessions, s)
+			}
+		}
+		if err := r.Insert(wd); err != nil {
+			return err
+		}
+	}
+	r.targets = append(r.targets, tables.Targets...)
+	for _, p := range tables.Projects {
+		if err := r.SaveProject(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FakeRepo) History(limit int) ([]db.Change, error) {
+	history := make([]db.Change, 0, limit)
+	for i := len(r.changes) - 1; i >= 0 && len(history) < limit; i-- {
+		history = append(history, r.changes[i])
+	}
+	return history, nil
+}
+
+func (r *FakeRepo) Undo() (*db.Change, error) {
+	for i := len(r.changes) - 1; i >= 0; i-- {
+		if c := &r.changes[i]; !c.Undone {
+			state, _ := c.Previous()
+			r.apply(c.Date, state)
+			c.Undone = true
+			return c, nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+func (r *FakeRepo) Redo() (*db.Change, error) {
+	for i := range r.changes {
+		if c := &r.changes[i]; c.Undone {
+			state, _ := c.Next()
+			r.apply(c.Date, state)
+			c.Undone = false
+			return c, nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+// journal records a change like the SqlRepo does
+func (r *FakeRepo) journal(action db.ChangeAction, before *db.WorkingDay, after *db.WorkingDay) {
+	kept := r.changes[:0]
+	for _, c := range r.changes {
+		if !c.Undone {
+			kept = append(kept, c)
+		}
+	}
+
+	c := db.Change{Action: action}
+	c.ID = uint(len(kept) + 1)
+	for _, state := range []struct {
+		wd   *db.WorkingDay
+		dest *string
+	}{{before, &c.Before}, {after, &c.After}} {
+		if state.wd != nil {
+			content, _ := json.Marshal(state.wd)
+			c.Date, *state.dest = state.wd.Date, string(content)
+		}
+	}
+	r.changes = append(kept, c)
+	r.audit = append(r.audit, db.AuditEntry{Date: c.Date, Action: action, User: "tester", OldValue: c.Before, NewValue: c.After})
+}
+
+func (r *FakeRepo) Audit(date *time.Time) ([]db.AuditEntry, error) {
+	entries := make([]db.AuditEntry, 0)
+	for _, e := range r.audit {
+		if date == nil || e.Date.Format("2006-01-02") == date.Format("2006-01-02") {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// apply replaces the working day of the date by the state
+func (r *FakeRepo) apply(date time.Time, state *db.WorkingDay) {
+	if state == nil {
+		delete(r.data, date.Format("2006-01-02"))
+		return
+	}
+	r.data[date.Format("2006-01-02")] = *state
+}
+
+// loadDay loads the working day of the date or nil
+func (r *FakeRepo) loadDay(d time.Time) *db.WorkingDay {
+	wd, _ := r.LoadDay(&d)
+	return wd
+}
+
+// loadProject loads the project or nil
+func (r *FakeRepo) loadProject(name string) *db.Project {
+	p, _ := r.LoadProject(name)
+	return p
+}
+
+// newWorkingDay creates a working day with a single session
+func newWorkingDay(start time.Time, end time.Time, brk int, note string) db.WorkingDay {
+	date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	return db.WorkingDay{Date: date, Sessions: []db.Session{{Start: start, End: &end}}, Brk: brk, Note: note}
+}
+
+// breakOf returns the break in minutes as it is passed by the break flag
+func breakOf(minutes int) *int {
+	return &minutes
+}
+
+// noBackup takes no backup before a change
+func noBackup() {}
@@ -0,0 +1,467 @@
+/*
+Package config contains the configuration of the timed cli
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v2"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+// EnvPrefix is the prefix of all environment variables which override the configuration
+const EnvPrefix = "TIMED_"
+
+var (
+	// extensions are the supported config file extensions in the order of lookup
+	extensions = []string{"yaml", "yml", "json"}
+
+	// Locales maps the name of a locale to its date layout
+	Locales = map[string]string{
+		"iso": "2006-01-02",
+		"de":  "02.01.2006",
+		"uk":  "02/01/2006",
+		"us":  "01/02/2006",
+	}
+
+	// Outputs contains all supported output formats
+	Outputs = []string{"table", "json", "csv", "markdown", "html"}
+
+	// ErrUnknownKey is returned for keys which are not part of the configuration
+	ErrUnknownKey = errors.New("unknown config key")
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// Config represents all settings of timed
+type Config struct {
+	DbPath       string  `yaml:"db_path" json:"db_path"`
+	TargetHours  float64 `yaml:"target_hours" json:"target_hours"`
+	ListDays     int     `yaml:"list_days" json:"list_days"`
+	Output       string  `yaml:"output" json:"output"`
+	Locale       string  `yaml:"locale" json:"locale"`
+	VacationDays int     `yaml:"vacation_days" json:"vacation_days"`
+	// TimeZone is the IANA name of the zone which determines the day boundaries. Empty means the zone of the system.
+	TimeZone string `yaml:"time_zone" json:"time_zone"`
+	// MaxDailyHours is the time worked on a day which causes a warning. Zero disables the warning.
+	MaxDailyHours float64 `yaml:"max_daily_hours" json:"max_daily_hours"`
+	// BreakRules contains the required breaks like "6h=30m,9h=45m". Empty disables the warning.
+	BreakRules string `yaml:"break_rules" json:"break_rules"`
+	// APIToken is the bearer token required by "timed serve". Empty disables the authentication.
+	APIToken string `yaml:"api_token" json:"api_token"`
+	// BackupDir is the directory of the backups of a SQLite database
+	BackupDir string `yaml:"backup_dir" json:"backup_dir"`
+	// BackupKeep is the number of backups which are kept. Zero disables the backups.
+	BackupKeep int `yaml:"backup_keep" json:"backup_keep"`
+	// BackupIntervalHours is the age of the latest backup which causes a new one. Zero disables the scheduled backups.
+	BackupIntervalHours float64 `yaml:"backup_interval_hours" json:"backup_interval_hours"`
+}
+
+// BreakRule demands a minimum break once more than After was worked
+type BreakRule struct {
+	After   time.Duration
+	Minimum time.Duration
+}
+
+// Default returns the configuration which is used without config file
+func Default() Config {
+	return Config{
+		DbPath:        filepath.Join("~", ".timed.db"),
+		TargetHours:   8,
+		ListDays:      30,
+		Output:        "table",
+		Locale:        "iso",
+		VacationDays:  30,
+		MaxDailyHours: 10,
+		BreakRules:    "6h=30m,9h=45m",
+
+		BackupDir:           filepath.Join("~", ".timed-backups"),
+		BackupKeep:          10,
+		BackupIntervalHours: 24,
+	}
+}
+
+// Keys returns all config keys
+func Keys() []string {
+	return []string{"api_token", "backup_dir", "backup_interval_hours", "backup_keep", "break_rules", "db_path", "list_days", "locale", "max_daily_hours", "output", "target_hours", "time_zone", "vacation_days"}
+}
+
+// Path finds the config file. It looks into $XDG_CONFIG_HOME/timed or ~/.config/timed for config.yaml,
+// config.yml or config.json. When none exists the path of config.yaml is returned.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	dir = filepath.Join(dir, "timed")
+
+	for _, ext := range extensions {
+		path := filepath.Join(dir, "config."+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return filepath.Join(dir, "config."+extensions[0]), nil
+}
+
+// Load reads the config file and fills all missing settings with defaults. A missing file is not an error.
+func Load(path string) (Config, error) {
+	c := Default()
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	// YAML is a superset of JSON, so both formats can be read the same way
+	if err = yaml.UnmarshalStrict(content, &c); err != nil {
+		return c, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+
+	return c, c.validate()
+}
+
+// Save writes the config file. The format is determined by the file extension.
+func (c *Config) Save(path string) error {
+	var content []byte
+	var err error
+
+	if filepath.Ext(path) == ".json" {
+		content, err = json.MarshalIndent(c, "", "  ")
+	} else {
+		content, err = yaml.Marshal(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// The file may contain the API token and the password of the database
+	if err = ioutil.WriteFile(path, content, 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
+
+// ApplyEnv overrides settings by environment variables. E.g. TIMED_DB_PATH overrides db_path.
+func (c *Config) ApplyEnv() error {
+	for _, key := range Keys() {
+		if value, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("invalid environment variable %s: %w", EnvPrefix+strings.ToUpper(key), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Redacted returns a setting like Get but hides secrets. The API token is masked and the password is removed from
+// the data source name of the database.
+func (c *Config) Redacted(key string) (string, error) {
+	value, err := c.Get(key)
+	if err != nil || value == "" {
+		return value, err
+	}
+
+	switch key {
+	case "api_token":
+		return "xxxxx", nil
+	case "db_path":
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			return u.Redacted(), nil
+		}
+	}
+	return value, nil
+}
+
+// Get returns a setting as string
+func (c *Config) Get(key string) (string, error) {
+	switch key {
+	case "db_path":
+		return c.DbPath, nil
+	case "target_hours":
+		return strconv.FormatFloat(c.TargetHours, 'f', -1, 64), nil
+	case "list_days":
+		return strconv.Itoa(c.ListDays), nil
+	case "output":
+		return c.Output, nil
+	case "locale":
+		return c.Locale, nil
+	case "vacation_days":
+		return strconv.Itoa(c.VacationDays), nil
+	case "time_zone":
+		return c.TimeZone, nil
+	case "max_daily_hours":
+		return strconv.FormatFloat(c.MaxDailyHours, 'f', -1, 64), nil
+	case "break_rules":
+		return c.BreakRules, nil
+	case "api_token":
+		return c.APIToken, nil
+	case "backup_dir":
+		return c.BackupDir, nil
+	case "backup_keep":
+		return strconv.Itoa(c.BackupKeep), nil
+	case "backup_interval_hours":
+		return strconv.FormatFloat(c.BackupIntervalHours, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("%w '%s'", ErrUnknownKey, key)
+}
+
+// Set changes a setting by its string representation
+func (c *Config) Set(key string, value string) error {
+	changed := *c
+
+	switch key {
+	case "db_path":
+		changed.DbPath = value
+	case "target_hours":
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		changed.TargetHours = hours
+	case "list_days":
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		changed.ListDays = days
+	case "output":
+		changed.Output = value
+	case "locale":
+		changed.Locale = value
+	case "vacation_days":
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		changed.VacationDays = days
+	case "time_zone":
+		changed.TimeZone = value
+	case "max_daily_hours":
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		changed.MaxDailyHours = hours
+	case "break_rules":
+		changed.BreakRules = value
+	case "api_token":
+		changed.APIToken = value
+	case "backup_dir":
+		changed.BackupDir = value
+	case "backup_keep":
+		keep, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		changed.BackupKeep = keep
+	case "backup_interval_hours":
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		changed.BackupIntervalHours = hours
+	default:
+		return fmt.Errorf("%w '%s'", ErrUnknownKey, key)
+	}
+
+	if err := changed.validate(); err != nil {
+		return err
+	}
+	*c = changed
+	return nil
+}
+
+// Database returns the path or data source name of the database with an expanded home directory
+func (c *Config) Database() (string, error) {
+	if i := strings.Index(c.DbPath, "://"); i > 0 {
+		path, err := homedir.Expand(c.DbPath[i+len("://"):])
+		return c.DbPath[:i+len("://")] + path, err
+	}
+	return homedir.Expand(c.DbPath)
+}
+
+// Backups returns the directory of the backups with an expanded home directory
+func (c *Config) Backups() (string, error) {
+	return homedir.Expand(c.BackupDir)
+}
+
+// BackupInterval returns the age of the latest backup which causes a new one. Zero means no scheduled backups.
+func (c *Config) BackupInterval() time.Duration {
+	return time.Duration(c.BackupIntervalHours * float64(time.Hour))
+}
+
+// Target returns the daily target hours as duration
+func (c *Config) Target() time.Duration {
+	return time.Duration(c.TargetHours * float64(time.Hour))
+}
+
+// DateLayout returns the date layout of the configured locale
+func (c *Config) DateLayout() string {
+	return Locales[c.Locale]
+}
+
+// MaxDaily returns the time worked on a day which causes a warning. Zero means no limit.
+func (c *Config) MaxDaily() time.Duration {
+	return time.Duration(c.MaxDailyHours * float64(time.Hour))
+}
+
+// Breaks parses the break rules. E.g. "6h=30m,9h=45m" requires 30 minutes break after 6 hours of work and
+// 45 minutes after 9 hours.
+func (c *Config) Breaks() ([]BreakRule, error) {
+	var rules []BreakRule
+	for _, rule := range strings.Split(c.BreakRules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		parts := strings.Split(rule, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid break rule '%s' - use e.g. 6h=30m", rule)
+		}
+		after, err := time.ParseDuration(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid break rule '%s': %w", rule, err)
+		}
+		minimum, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid break rule '%s': %w", rule, err)
+		}
+		if after <= 0 || minimum <= 0 {
+			return nil, fmt.Errorf("invalid break rule '%s' - durations must be positive", rule)
+		}
+		rules = append(rules, BreakRule{After: after, Minimum: minimum})
+	}
+	return rules, nil
+}
+
+// Location returns the configured time zone. Without one the zone of the system is looked up by its name, so it
+// can be stored along with the times.
+func (c *Config) Location() (*time.Location, error) {
+	if c.TimeZone != "" {
+		return time.LoadLocation(c.TimeZone)
+	}
+
+	if name := os.Getenv("TZ"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, nil
+		}
+	}
+	if link, err := os.Readlink("/etc/localtime"); err == nil {
+		if i := strings.LastIndex(link, "zoneinfo/"); i >= 0 {
+			if loc, err := time.LoadLocation(link[i+len("zoneinfo/"):]); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return time.Local, nil
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+func (c *Config) validate() error {
+	if c.DbPath == "" {
+		return errors.New("db_path must not be empty")
+	}
+	if c.TargetHours < 0 || c.TargetHours > 24 {
+		return errors.New("target_hours must be between 0 and 24")
+	}
+	if c.ListDays < 0 {
+		return errors.New("list_days must not be negative")
+	}
+	if c.VacationDays < 0 || c.VacationDays > 366 {
+		return errors.New("vacation_days must be between 0 and 366")
+	}
+	if !contains(Outputs, c.Output) {
+		return fmt.Errorf("output must be one of %s", strings.Join(Outputs, ", "))
+	}
+	if _, ok := Locales[c.Locale]; !ok {
+		locales := make([]string, 0, len(Locales))
+		for l := range Locales {
+			locales = append(locales, l)
+		}
+		sort.Strings(locales)
+		return fmt.Errorf("locale must be one of %s", strings.Join(locales, ", "))
+	}
+	if c.MaxDailyHours < 0 || c.MaxDailyHours > 24 {
+		return errors.New("max_daily_hours must be between 0 and 24")
+	}
+	if c.BackupDir == "" {
+		return errors.New("backup_dir must not be empty")
+	}
+	if c.BackupKeep < 0 {
+		return errors.New("backup_keep must not be negative")
+	}
+	if c.BackupIntervalHours < 0 {
+		return errors.New("backup_interval_hours must not be negative")
+	}
+	if _, err := c.Breaks(); err != nil {
+		return fmt.Errorf("break_rules: %w", err)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("time_zone must be an IANA time zone like Europe/Berlin: %w", err)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != Default() {
+		t.Fatalf("Expected defaults but got %+v", c)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	for _, name := range []string{"config.yaml", "config.json"} {
+		path := filepath.Join(t.TempDir(), "timed", name)
+
+		c := Default()
+		if err := c.Set("target_hours", "6.5"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Set("locale", "de"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded != c {
+			t.Fatalf("Expected %+v from %s but got %+v", c, name, loaded)
+		}
+		if loaded.Target() != 6*time.Hour+30*time.Minute || loaded.DateLayout() != "02.01.2006" {
+			t.Fatalf("Unexpected target or layout from %s: %+v", name, loaded)
+		}
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	for _, content := range []string{"unknown: 1", "list_days: -1", "locale: xx", "output: pdf", "time_zone: Mars/Olympus", "max_daily_hours: 25", "break_rules: 6h", "backup_keep: -1"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Fatalf("Expected error for '%s'", content)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	setenv(t, "TIMED_DB_PATH", "/tmp/other.db")
+	setenv(t, "TIMED_LIST_DAYS", "7")
+
+	c := Default()
+	if err := c.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if c.DbPath != "/tmp/other.db" || c.ListDays != 7 {
+		t.Fatalf("Environment was not applied: %+v", c)
+	}
+
+	setenv(t, "TIMED_LIST_DAYS", "many")
+	if err := c.ApplyEnv(); err == nil {
+		t.Fatal("Expected error for invalid environment variable")
+	}
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	setenv(t, "XDG_CONFIG_HOME", dir)
+
+	path, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "timed", "config.yaml") {
+		t.Fatalf("Unexpected default path %s", path)
+	}
+
+	jsonPath := filepath.Join(dir, "timed", "config.json")
+	c := Default()
+	if err := c.Save(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ = Path(); path != jsonPath {
+		t.Fatalf("Expected existing %s but got %s", jsonPath, path)
+	}
+}
+
+func TestGetSetUnknownKey(t *testing.T) {
+	c := Default()
+	if _, err := c.Get("foo"); err == nil {
+		t.Fatal("Expected error for unknown key")
+	}
+	if err := c.Set("foo", "bar"); err == nil {
+		t.Fatal("Expected error for unknown key")
+	}
+	if err := c.Set("target_hours", "25"); err == nil || c.TargetHours != 8 {
+		t.Fatal("Accepted invalid target hours")
+	}
+}
+
+func TestBreaks(t *testing.T) {
+	c := Default()
+	rules, err := c.Breaks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0] != (BreakRule{6 * time.Hour, 30 * time.Minute}) || rules[1] != (BreakRule{9 * time.Hour, 45 * time.Minute}) {
+		t.Fatalf("Unexpected default break rules: %v", rules)
+	}
+
+	if err = c.Set("break_rules", ""); err != nil {
+		t.Fatal(err)
+	}
+	if rules, err = c.Breaks(); err != nil || len(rules) != 0 {
+		t.Fatalf("Expected no break rules but got %v (%v)", rules, err)
+	}
+
+	for _, invalid := range []string{"6h", "6h=30", "six=30m", "6h=-30m"} {
+		if err = c.Set("break_rules", invalid); err == nil {
+			t.Fatalf("Accepted invalid break rules '%s'", invalid)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	c := Default()
+	if err := c.Set("time_zone", "Asia/Tokyo"); err != nil {
+		t.Fatal(err)
+	}
+	if loc, err := c.Location(); err != nil || loc.String() != "Asia/Tokyo" {
+		t.Fatalf("Expected Asia/Tokyo but got %v (%v)", loc, err)
+	}
+
+	// Without a configured zone the one of the system is used
+	setenv(t, "TZ", "America/New_York")
+	c.TimeZone = ""
+	if loc, err := c.Location(); err != nil || loc.String() != "America/New_York" {
+		t.Fatalf("Expected the zone of the system but got %v (%v)", loc, err)
+	}
+
+	if err := c.Set("time_zone", "Mars/Olympus"); err == nil || c.TimeZone != "" {
+		t.Fatal("Accepted an unknown time zone")
+	}
+}
+
+// setenv sets an environment variable until the end of the test
+func setenv(t *testing.T, key string, value string) {
+	old, existed := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if existed {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// ArchiveFormat identifies timed archives
+	ArchiveFormat = "timed-archive"
+	// ArchiveVersion is the newest archive version this package writes and reads
+	ArchiveVersion = 1
+)
+
+// ArchiveTables contains every record of every table including soft deleted ones
+type ArchiveTables struct {
+	WorkingDays []WorkingDay `json:"working_days"`
+	Sessions    []Session    `json:"sessions"`
+	Targets     []Target     `json:"targets"`
+	Projects    []Project    `json:"projects"`
+	// Changes and audit entries are omitted when empty to keep the checksum of archives without them
+	Changes      []Change     `json:"changes,omitempty"`
+	AuditEntries []AuditEntry `json:"audit_entries,omitempty"`
+}
+
+// Archive is a self-describing copy of a whole database
+type Archive struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Generator string    `json:"generator"`
+	// Checksum is the SHA-256 of the JSON encoded tables
+	Checksum string `json:"checksum"`
+
+	Tables ArchiveTables `json:"tables"`
+}
+
+// archiveHeader is the first line of an NDJSON archive
+type archiveHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Generator string    `json:"generator"`
+	Checksum  string    `json:"checksum"`
+}
+
+// archiveLine is one record of an NDJSON archive
+type archiveLine struct {
+	Table  string          `json:"table"`
+	Record json.RawMessage `json:"record"`
+}
+
+// NewArchive creates an archive of the tables
+func NewArchive(tables ArchiveTables, generator string) (*Archive, error) {
+	checksum, err := tables.checksum()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Format:    ArchiveFormat,
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		Generator: generator,
+		Checksum:  checksum,
+		Tables:    tables,
+	}, nil
+}
+
+// WriteJSON writes the archive as one JSON document
+func (a *Archive) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(a)
+}
+
+// WriteNDJSON writes the archive header followed by one line per record
+func (a *Archive) WriteNDJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	if err := encoder.Encode(archiveHeader{a.Format, a.Version, a.CreatedAt, a.Generator, a.Checksum}); err != nil {
+		return err
+	}
+
+	write := func(table string, record interface{}) error {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(archiveLine{Table: table, Record: raw})
+	}
+
+	for _, r := range a.Tables.WorkingDays {
+		if err := write("working_days", r); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Tables.Sessions {
+		if err := write("sessions", r); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Tables.Targets {
+		if err := write("targets", r); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Tables.Projects {
+		if err := write("projects", r); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Tables.Changes {
+		if err := write("changes", r); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Tables.AuditEntries {
+		if err := write("audit_entries", r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadArchive reads a JSON or NDJSON archive and verifies it
+func ReadArchive(r io.Reader) (*Archive, error) {
+	decoder := json.NewDecoder(r)
+
+	a := &Archive{}
+	if err := decoder.Decode(a); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	for decoder.More() {
+		var line archiveLine
+		if err := decoder.Decode(&line); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		var err error
+		switch line.Table {
+		case "working_days":
+			var wd WorkingDay
+			err = json.Unmarshal(line.Record, &wd)
+			a.Tables.WorkingDays = append(a.Tables.WorkingDays, wd)
+		case "sessions":
+			var s Session
+			err = json.Unmarshal(line.Record, &s)
+			a.Tables.Sessions = append(a.Tables.Sessions, s)
+		case "targets":
+			var t Target
+			err = json.Unmarshal(line.Record, &t)
+			a.Tables.Targets = append(a.Tables.Targets, t)
+		case "projects":
+			var p Project
+			err = json.Unmarshal(line.Record, &p)
+			a.Tables.Projects = append(a.Tables.Projects, p)
+		case "changes":
+			var c Change
+			err = json.Unmarshal(line.Record, &c)
+			a.Tables.Changes = append(a.Tables.Changes, c)
+		case "audit_entries":
+			var e AuditEntry
+			err = json.Unmarshal(line.Record, &e)
+			a.Tables.AuditEntries = append(a.Tables.AuditEntries, e)
+		default:
+			err = fmt.Errorf("unknown table '%s'", line.Table)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+	}
+
+	return a, a.Verify()
+}
+
+// Verify checks format, version and checksum of the archive
+func (a *Archive) Verify() error {
+	if a.Format != ArchiveFormat {
+		return fmt.Errorf("%w: unknown format '%s'", ErrInvalidArchive, a.Format)
+	}
+	if a.Version < 1 || a.Version > ArchiveVersion {
+		return fmt.Errorf("%w: version %d is not supported - newest supported version is %d", ErrInvalidArchive, a.Version, ArchiveVersion)
+	}
+
+	checksum, err := a.Tables.checksum()
+	if err != nil {
+		return err
+	}
+	if checksum != a.Checksum {
+		return fmt.Errorf("%w: checksum mismatch - the archive is damaged", ErrInvalidArchive)
+	}
+	return nil
+}
+
+func (t *ArchiveTables) checksum() (string, error) {
+	// Empty tables are always encoded the same way
+	normalized := ArchiveTables{
+		WorkingDays:  append([]WorkingDay{}, t.WorkingDays...),
+		Sessions:     append([]Session{}, t.Sessions...),
+		Targets:      append([]Target{}, t.Targets...),
+		Projects:     append([]Project{}, t.Projects...),
+		Changes:      append([]Change{}, t.Changes...),
+		AuditEntries: append([]AuditEntry{}, t.AuditEntries...),
+	}
+
+	content, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSqlRepo_DumpAndRestore(t *testing.T) {
+
+	const restoredName = "restored.db"
+	defer os.Remove(dbName)
+	defer os.Remove(restoredName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	deleted := start.AddDate(0, 0, 1)
+	for _, wd := range []WorkingDay{
+		newWorkingDay(start, start.Add(time.Hour), 15, "kept"),
+		newWorkingDay(deleted, deleted.Add(time.Hour), 0, "deleted"),
+	} {
+		if err := repo.Insert(wd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := repo.LoadDay(&deleted)
+	if err := repo.Delete(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveProject(Project{Name: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := repo.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables.WorkingDays) != 2 || len(tables.Sessions) != 2 || len(tables.Projects) != 1 {
+		t.Fatalf("Dump misses records: %+v", tables)
+	}
+
+	restored, err := NewRepo(restoredName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.Restore(tables); err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.Restore(tables); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("Restored into a database with records: %v", err)
+	}
+
+	wd, err = restored.LoadDay(&start)
+	if err != nil || wd.Note != "kept" || wd.Brk != 15 || len(wd.Sessions) != 1 || wd.ID != tables.WorkingDays[0].ID {
+		t.Fatalf("Restored unexpected day %v", wd)
+	}
+	if _, err = restored.LoadDay(&deleted); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Deleted day was restored as live day")
+	}
+
+	again, err := restored.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.WorkingDays[1].DeletedAt.Valid || len(again.Sessions) != 2 {
+		t.Fatalf("Soft deleted records were not restored: %+v", again)
+	}
+}
+
+func TestArchive_ReadWrite(t *testing.T) {
+
+	end := time.Date(2020, 10, 8, 9, 0, 00, 000, time.UTC)
+	tables := ArchiveTables{
+		WorkingDays: []WorkingDay{{Date: end.Truncate(24 * time.Hour), Note: "note"}},
+		Sessions:    []Session{{WorkingDayID: 1, Start: end.Add(-time.Hour), End: &end, Project: "acme"}},
+	}
+	tables.WorkingDays[0].ID = 1
+
+	archive, err := NewArchive(tables, "timed test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"json":   func(b *bytes.Buffer) error { return archive.WriteJSON(b) },
+		"ndjson": func(b *bytes.Buffer) error { return archive.WriteNDJSON(b) },
+	} {
+		b := &bytes.Buffer{}
+		if err = write(b); err != nil {
+			t.Fatal(err)
+		}
+		content := b.String()
+
+		read, err := ReadArchive(strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if read.Checksum != archive.Checksum || len(read.Tables.Sessions) != 1 || read.Tables.Sessions[0].Project != "acme" {
+			t.Fatalf("%s: read unexpected archive %+v", name, read)
+		}
+
+		// Any change of a record is detected
+		tampered := strings.Replace(content, "acme", "acne", 1)
+		if _, err = ReadArchive(strings.NewReader(tampered)); !errors.Is(err, ErrInvalidArchive) {
+			t.Fatalf("%s: accepted tampered archive: %v", name, err)
+		}
+	}
+
+	archive.Version = ArchiveVersion + 1
+	if err = archive.Verify(); !errors.Is(err, ErrInvalidArchive) {
+		t.Fatalf("Accepted unsupported version: %v", err)
+	}
+}
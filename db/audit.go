@@ -0,0 +1,117 @@
+package db
+
+import (
+	"os"
+	"os/user"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Reverted means that a change was undone
+	Reverted ChangeAction = "undo"
+	// Reapplied means that an undone change was applied again
+	Reapplied ChangeAction = "redo"
+)
+
+// AuditEntry records who altered a working day when. Audit entries are never updated or deleted.
+type AuditEntry struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	WorkingDayID uint      `gorm:"index"`
+	Date         time.Time `gorm:"index"`
+	Action       ChangeAction
+	User         string
+
+	// OldValue and NewValue contain the working day with its sessions as JSON. They are empty when the day did not exist.
+	OldValue string
+	NewValue string
+}
+
+// Previous returns the working day before the alteration or nil when it did not exist
+func (a *AuditEntry) Previous() (*WorkingDay, error) {
+	return decodeState(a.OldValue)
+}
+
+// Next returns the working day after the alteration or nil when it was deleted
+func (a *AuditEntry) Next() (*WorkingDay, error) {
+	return decodeState(a.NewValue)
+}
+
+// auditTriggers make the audit table append-only. They are keyed by the name of the database dialect.
+var auditTriggers = map[string][]string{
+	"sqlite": {
+		"CREATE TRIGGER IF NOT EXISTS audit_entries_no_update BEFORE UPDATE ON audit_entries " +
+			"BEGIN SELECT RAISE(ABORT, 'audit entries are append-only'); END",
+		"CREATE TRIGGER IF NOT EXISTS audit_entries_no_delete BEFORE DELETE ON audit_entries " +
+			"BEGIN SELECT RAISE(ABORT, 'audit entries are append-only'); END",
+	},
+	"postgres": {
+		"CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$ " +
+			"BEGIN RAISE EXCEPTION 'audit entries are append-only'; END $$ LANGUAGE plpgsql",
+		"DROP TRIGGER IF EXISTS audit_entries_no_change ON audit_entries",
+		"CREATE TRIGGER audit_entries_no_change BEFORE UPDATE OR DELETE ON audit_entries " +
+			"FOR EACH ROW EXECUTE PROCEDURE audit_entries_append_only()",
+	},
+}
+
+// auditTriggerDrops remove the audit triggers again
+var auditTriggerDrops = map[string][]string{
+	"sqlite": {
+		"DROP TRIGGER IF EXISTS audit_entries_no_update",
+		"DROP TRIGGER IF EXISTS audit_entries_no_delete",
+	},
+	"postgres": {
+		"DROP TRIGGER IF EXISTS audit_entries_no_change ON audit_entries",
+		"DROP FUNCTION IF EXISTS audit_entries_append_only()",
+	},
+}
+
+// audit appends an entry for the alteration of a working day to the audit table
+func audit(tx *gorm.DB, action ChangeAction, before *WorkingDay, after *WorkingDay) error {
+	c, err := newChange(action, before, after)
+	if err != nil {
+		return err
+	}
+
+	entry := AuditEntry{Date: c.Date, Action: action, User: osUser(), OldValue: c.Before, NewValue: c.After}
+	if after != nil {
+		entry.WorkingDayID = after.ID
+	} else if before != nil {
+		entry.WorkingDayID = before.ID
+	}
+	return tx.Create(&entry).Error
+}
+
+// osUser returns the name of the user of the operating system
+func osUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Audit loads the audit entries of a date in chronological order. All entries are loaded when date is nil.
+func (r *SqlRepo) Audit(date *time.Time) ([]AuditEntry, error) {
+	var entries []AuditEntry
+
+	tx := r.db.Order("id")
+	if date != nil && r.db.Dialector.Name() == "sqlite" {
+		// Entries written before dates were stored in UTC contain the local midnight, so only the day is compared
+		tx = tx.Where("substr(date, 1, 10) = ?", date.Format("2006-01-02"))
+	} else if date != nil {
+		s, e := startEnd(date)
+		tx = tx.Where("date BETWEEN ? and ?", s, e)
+	}
+	if err := tx.Find(&entries).Error; err != nil {
+		return nil, wrap(err, "load audit entries")
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSqlRepo_Audit(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	if err := repo.Insert(newWorkingDay(start, start.Add(8*time.Hour), 30, "original")); err != nil {
+		t.Fatal(err)
+	}
+	other := start.AddDate(0, 0, 1)
+	if err := repo.Insert(newWorkingDay(other, other.Add(time.Hour), 0, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := repo.LoadDay(&start)
+	wd.Sessions[0].Start = start.Add(-time.Hour)
+	if err := repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := repo.Audit(&start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []ChangeAction{Inserted, Updated, Deleted, Reverted}
+	if len(entries) != len(actions) {
+		t.Fatalf("Expected %d audit entries but got %d", len(actions), len(entries))
+	}
+	for i, e := range entries {
+		if e.Action != actions[i] || e.User == "" || e.WorkingDayID != wd.ID {
+			t.Fatalf("Unexpected audit entry %+v", e)
+		}
+	}
+
+	old, _ := entries[1].Previous()
+	updated, _ := entries[1].Next()
+	if !old.Start().Equal(start) || !updated.Start().Equal(start.Add(-time.Hour)) {
+		t.Fatalf("Audit entry does not keep old and new value: %s -> %s", old, updated)
+	}
+	if deleted, _ := entries[2].Next(); deleted != nil {
+		t.Fatal("Audit entry of delete has a new value")
+	}
+
+	all, _ := repo.Audit(nil)
+	if len(all) != len(actions)+1 {
+		t.Fatalf("Expected all %d audit entries but got %d", len(actions)+1, len(all))
+	}
+
+	// Audit entries are append-only
+	if err = repo.db.Delete(&AuditEntry{}, entries[0].ID).Error; err == nil {
+		t.Fatal("Deleted audit entry")
+	}
+	if err = repo.db.Model(&entries[0]).Update("user", "someone").Error; err == nil {
+		t.Fatal("Updated audit entry")
+	}
+}
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	// backupLayout names backups after the UTC time they were taken
+	backupLayout = "20060102-150405"
+	backupPrefix = "timed-"
+	backupSuffix = ".db"
+)
+
+// Backup is a consistent copy of a SQLite database
+type Backup struct {
+	// ID is the UTC time the backup was taken like 20240328-174501. Backups taken within the same second are
+	// numbered like 20240328-174501-2.
+	ID        string
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// CreateBackup copies the SQLite database at the path into the directory. The database may be in use meanwhile, the
+// copy contains a consistent state anyway. The copy is verified and only the newest keep backups are kept. All
+// backups are kept when keep is less than 1.
+func CreateBackup(dbPath string, dir string, keep int) (*Backup, error) {
+	if !fileExists(dbPath) {
+		return nil, fmt.Errorf("%w: database '%s'", ErrNotFound, dbPath)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, wrap(err, "back up database '%s'", dbPath)
+	}
+
+	taken := time.Now().UTC().Format(backupLayout)
+	id := taken
+	path := filepath.Join(dir, backupPrefix+id+backupSuffix)
+	for n := 2; fileExists(path); n++ {
+		id = fmt.Sprintf("%s-%d", taken, n)
+		path = filepath.Join(dir, backupPrefix+id+backupSuffix)
+	}
+
+	schema, err := openSchema(sqlite.Open(dbPath), dbPath, "")
+	if err != nil {
+		return nil, err
+	}
+	err = vacuumInto(schema.db, path)
+	schema.close()
+	if err != nil {
+		os.Remove(path)
+		return nil, wrap(err, "back up database '%s'", dbPath)
+	}
+	if err = VerifyBackup(path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if err = rotateBackups(dir, keep); err != nil {
+		return nil, err
+	}
+	return FindBackup(dir, id)
+}
+
+// Backups lists the backups in the directory starting with the newest one. A missing directory contains no backups.
+func Backups(dir string) ([]Backup, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrap(err, "list backups in '%s'", dir)
+	}
+
+	var backups []Backup
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:        strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix),
+			Path:      filepath.Join(dir, name),
+			CreatedAt: info.ModTime(),
+			Size:      info.Size(),
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		ti, ni, okI := parseBackupID(backups[i].ID)
+		tj, nj, okJ := parseBackupID(backups[j].ID)
+		switch {
+		case okI != okJ:
+			return okI
+		case !okI:
+			return backups[i].ID > backups[j].ID
+		case !ti.Equal(tj):
+			return ti.After(tj)
+		default:
+			return ni > nj
+		}
+	})
+	return backups, nil
+}
+
+// parseBackupID returns the time and the number of a backup ID like 20240328-174501-2. The first backup of a second
+// has the number 1.
+func parseBackupID(id string) (time.Time, int, bool) {
+	if len(id) < len(backupLayout) {
+		return time.Time{}, 0, false
+	}
+	taken, err := time.Parse(backupLayout, id[:len(backupLayout)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	n := 1
+	if rest := id[len(backupLayout):]; rest != "" {
+		if !strings.HasPrefix(rest, "-") {
+			return time.Time{}, 0, false
+		}
+		if n, err = strconv.Atoi(rest[1:]); err != nil || n < 2 {
+			return time.Time{}, 0, false
+		}
+	}
+	return taken, n, true
+}
+
+// FindBackup finds the backup with the id in the directory
+func FindBackup(dir string, id string) (*Backup, error) {
+	backups, err := Backups(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range backups {
+		if backups[i].ID == id {
+			return &backups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: backup '%s' in '%s'", ErrNotFound, id, dir)
+}
+
+// VerifyBackup checks that the backup is an intact timed database with a known schema version
+func VerifyBackup(path string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("%w: backup '%s'", ErrNotFound, path)
+	}
+
+	schema, err := openSchema(sqlite.Open("file:"+path+"?mode=ro"), path, "")
+	if err != nil {
+		return fmt.Errorf("%w '%s': %s", ErrInvalidBackup, path, err)
+	}
+	defer schema.close()
+
+	var results []string
+	if err = schema.db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("%w '%s': %s", ErrInvalidBackup, path, err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("%w '%s': %s", ErrInvalidBackup, path, strings.Join(results, ", "))
+	}
+
+	if !schema.db.Migrator().HasTable(&WorkingDay{}) {
+		return fmt.Errorf("%w '%s': no timed database", ErrInvalidBackup, path)
+	}
+	version, err := schema.Version()
+	if err != nil {
+		return fmt.Errorf("%w '%s': %s", ErrInvalidBackup, path, err)
+	}
+	if version > LatestVersion() {
+		return fmt.Errorf("%w '%s': schema version %d is newer than %d", ErrInvalidBackup, path, version, LatestVersion())
+	}
+	return nil
+}
+
+// RestoreBackup replaces the SQLite database at the path by the backup after verifying it. The database must not
+// be in use.
+func RestoreBackup(b Backup, dbPath string) error {
+	if err := VerifyBackup(b.Path); err != nil {
+		return err
+	}
+
+	src, err := os.Open(b.Path)
+	if err != nil {
+		return wrap(err, "restore backup '%s'", b.ID)
+	}
+	defer src.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(dbPath), filepath.Base(dbPath)+".*")
+	if err != nil {
+		return wrap(err, "restore backup '%s'", b.ID)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, src); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return wrap(err, "restore backup '%s'", b.ID)
+	}
+
+	// A journal of the replaced database would be applied to the backup
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err = os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return wrap(err, "restore backup '%s'", b.ID)
+		}
+	}
+	return wrap(os.Rename(tmp.Name(), dbPath), "restore backup '%s'", b.ID)
+}
+
+// rotateBackups removes all but the newest keep backups. Nothing is removed when keep is less than 1.
+func rotateBackups(dir string, keep int) error {
+	if keep < 1 {
+		return nil
+	}
+
+	backups, err := Backups(dir)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err = os.Remove(backups[i].Path); err != nil {
+			return wrap(err, "remove backup '%s'", backups[i].ID)
+		}
+	}
+	return nil
+}
+
+// vacuumInto writes a consistent copy of the SQLite database to the path
+func vacuumInto(db *gorm.DB, path string) error {
+	return db.Exec("VACUUM INTO ?", path).Error
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timed-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "timed.db")
+	backups := filepath.Join(dir, "backups")
+
+	if _, err = CreateBackup(path, backups, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing database but got %v", err)
+	}
+
+	repo, err := NewRepo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2021, 6, 1, 8, 0, 0, 0, time.Local)
+	if err = repo.Insert(newWorkingDay(day, day.Add(8*time.Hour), 0, "backed up")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The database stays open while it is backed up
+	var taken []*Backup
+	for i := 0; i < 3; i++ {
+		b, err := CreateBackup(path, backups, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taken = append(taken, b)
+	}
+	if taken[0].ID == taken[1].ID || taken[1].ID == taken[2].ID {
+		t.Fatalf("Expected unique IDs but got %s, %s and %s", taken[0].ID, taken[1].ID, taken[2].ID)
+	}
+	if at, _, ok := parseBackupID(taken[2].ID); !ok || time.Since(at) > time.Minute || time.Since(at) < -time.Minute {
+		t.Fatalf("Expected an ID of the current UTC time but got %s", taken[2].ID)
+	}
+
+	list, err := Backups(backups)
+	if err != nil || len(list) != 2 || list[0].ID != taken[2].ID || list[1].ID != taken[1].ID {
+		t.Fatalf("Expected the newest two backups but got %v (%v)", list, err)
+	}
+	if _, err = FindBackup(backups, taken[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the oldest backup to be removed but got %v", err)
+	}
+	if list, err = Backups(filepath.Join(dir, "missing")); err != nil || len(list) != 0 {
+		t.Errorf("Expected no backups in a missing directory but got %v (%v)", list, err)
+	}
+
+	if err = repo.Delete(*loadOf(t, repo, day)); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := repo.db.DB()
+	sqlDB.Close()
+
+	if err = RestoreBackup(*taken[2], path); err != nil {
+		t.Fatal(err)
+	}
+	if repo, err = NewRepo(path); err != nil {
+		t.Fatal(err)
+	}
+	if wd := loadOf(t, repo, day); wd.Note != "backed up" {
+		t.Errorf("Expected the day of the backup but got %s", wd)
+	}
+
+	damaged := filepath.Join(backups, "timed-damaged.db")
+	if err = ioutil.WriteFile(damaged, []byte("this is not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyBackup(damaged); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected an invalid backup but got %v", err)
+	}
+	if err = RestoreBackup(Backup{ID: "damaged", Path: damaged}, path); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected the damaged backup to be refused but got %v", err)
+	}
+}
+
+// loadOf loads the working day of the date
+func loadOf(t *testing.T, repo Repo, date time.Time) *WorkingDay {
+	wd, err := repo.LoadDay(&date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wd
+}
+
+func TestBackupsOrder(t *testing.T) {
+	dir := t.TempDir()
+	ids := []string{"20210601-120000-9", "other", "20210601-115959", "20210601-120000-10", "20210601-120001", "20210601-120000"}
+	for _, id := range ids {
+		if err := ioutil.WriteFile(filepath.Join(dir, backupPrefix+id+backupSuffix), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, b := range backups {
+		order = append(order, b.ID)
+	}
+	expected := "20210601-120001 20210601-120000-10 20210601-120000-9 20210601-120000 20210601-115959 other"
+	if strings.Join(order, " ") != expected {
+		t.Fatalf("Expected the newest backup first but got %v", order)
+	}
+}
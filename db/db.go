@@ -1,10 +1,11 @@
 package db
 
 import (
-	"gorm.io/gorm/logger"
+	"fmt"
+
+	"gorm.io/gorm/clause"
 	"time"
 
-	jww "github.com/spf13/jwalterweatherman"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -13,122 +14,417 @@ import (
 // ===== db =====
 // ==============
 
-// NewRepo creates and initiates a new repo
-func NewRepo(dbPath string) *SqlRepo {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		jww.ERROR.Fatal(err)
-	}
+// NewRepo creates and initiates a new repo on the SQLite database at the path. A database with data is copied before
+// it is migrated.
+func NewRepo(dbPath string) (*SqlRepo, error) {
+	return newSqlRepo(sqlite.Open(dbPath), dbPath, dbPath)
+}
 
-	err = db.AutoMigrate(&WorkingDay{})
+// newSqlRepo opens the database of the dialector and applies all pending migrations. The name describes the database
+// in errors. A SQLite database file is copied before it is migrated.
+func newSqlRepo(dialector gorm.Dialector, name string, file string) (*SqlRepo, error) {
+	schema, err := openSchema(dialector, name, file)
 	if err != nil {
-		jww.ERROR.Fatal(err)
+		return nil, err
+	}
+	if _, err = schema.Migrate(LatestVersion()); err != nil {
+		return nil, err
 	}
 
-	return &SqlRepo{db}
+	return &SqlRepo{db: schema.db, DefaultTarget: DefaultTarget}, nil
 }
 
 // ================
 // ===== REPO =====
 // ================
 
-// Repo is an interface for storing working days
+// Repo is an interface for storing working days. Missing records are reported with ErrNotFound.
 type Repo interface {
-	LoadDay(d *time.Time) *WorkingDay
-	Insert(wd WorkingDay)
-	UpdateDay(wd WorkingDay)
-	Delete(wd WorkingDay)
-	Overtime() int
+	LoadDay(d *time.Time) (*WorkingDay, error)
+	Insert(wd WorkingDay) error
+	UpdateDay(wd WorkingDay) error
+	Delete(wd WorkingDay) error
+	LoadRunning() (*WorkingDay, error)
+	Overtime() (int, error)
 	ListRange(start *time.Time, end *time.Time) ([]WorkingDay, error)
+	Schedule() (Schedule, error)
+	SetTarget(t Target) error
+	LoadProject(name string) (*Project, error)
+	SaveProject(p Project) error
+	Projects() ([]Project, error)
+	Transaction(fc func(tx Repo) error) error
+	Dump() (ArchiveTables, error)
+	Restore(tables ArchiveTables) error
+	History(limit int) ([]Change, error)
+	Undo() (*Change, error)
+	Redo() (*Change, error)
+	Audit(date *time.Time) ([]AuditEntry, error)
+	Trash() ([]WorkingDay, error)
+	RestoreDeleted(id uint) (*WorkingDay, error)
+	Purge(before time.Time) (int, error)
 }
 
 // SqlRepo represents a DB access layer
 type SqlRepo struct {
 	db *gorm.DB
+
+	// DefaultTarget applies to all days before the first stored target
+	DefaultTarget time.Duration
 }
 
 // LoadDay finds the matching working time entry for a specific date.
-func (r *SqlRepo) LoadDay(d *time.Time) *WorkingDay {
+func (r *SqlRepo) LoadDay(d *time.Time) (*WorkingDay, error) {
 
 	wd := &WorkingDay{}
 	s, e := startEnd(d)
-	tx := r.db.Where("start BETWEEN ? and ?", s, e).First(&wd)
+	tx := r.db.Preload("Sessions", orderSessions).Where("date BETWEEN ? and ?", s, e).First(&wd)
 
 	if tx.Error != nil {
-		jww.DEBUG.Printf("Could not load working day '%s': %s", d, tx.Error)
-		return nil
+		return nil, wrap(tx.Error, "load working day %s", d.Format("2006-01-02"))
 	}
-	return wd
+	return wd, nil
 }
 
-// UpdateDay updates the values of a working day in the database
-func (r *SqlRepo) UpdateDay(wd WorkingDay) {
+// LoadRunning finds the working day which has a session without an end.
+func (r *SqlRepo) LoadRunning() (*WorkingDay, error) {
+
+	var session Session
+	tx := r.db.Where(clause.Eq{Column: clause.Column{Name: "end"}, Value: nil}).Order("start DESC").First(&session)
+	if tx.Error != nil {
+		return nil, wrap(tx.Error, "load running session")
+	}
 
-	tx := r.db.Save(&wd)
+	wd := &WorkingDay{}
+	tx = r.db.Preload("Sessions", orderSessions).First(&wd, session.WorkingDayID)
 	if tx.Error != nil {
-		jww.ERROR.Fatal(tx.Error)
+		return nil, wrap(tx.Error, "load working day of running session %d", session.ID)
 	}
+	return wd, nil
+}
+
+// UpdateDay updates the values of a working day and its sessions in the database. The change is journaled.
+func (r *SqlRepo) UpdateDay(wd WorkingDay) error {
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if wd.ID == 0 {
+			return ErrNotFound
+		}
+		before := &WorkingDay{}
+		if err := tx.Preload("Sessions", orderSessions).First(before, wd.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Omit("Sessions").Save(&wd).Error; err != nil {
+			return err
+		}
+
+		keep := make([]uint, 0, len(wd.Sessions))
+		for i := range wd.Sessions {
+			wd.Sessions[i].WorkingDayID = wd.ID
+			if err := tx.Save(&wd.Sessions[i]).Error; err != nil {
+				return err
+			}
+			keep = append(keep, wd.Sessions[i].ID)
+		}
+
+		// Sessions which are no longer part of the day are removed
+		obsolete := tx.Where("working_day_id = ? AND deleted_at IS NULL", wd.ID)
+		if len(keep) > 0 {
+			obsolete = obsolete.Where("id NOT IN ?", keep)
+		}
+		if err := obsolete.Delete(&Session{}).Error; err != nil {
+			return err
+		}
+
+		return record(tx, Updated, before, &wd)
+	})
+
+	return wrap(err, "update working day %s", wd.Date.Format("2006-01-02"))
+}
+
+// Insert adds a new working day with its sessions to the database. There must not be another working day on the same date.
+// The change is journaled.
+func (r *SqlRepo) Insert(wd WorkingDay) error {
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		s, e := startEnd(&wd.Date)
+		if err := tx.Model(&WorkingDay{}).Where("date BETWEEN ? and ?", s, e).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrConflict
+		}
+
+		if err := tx.Create(&wd).Error; err != nil {
+			return err
+		}
+		return record(tx, Inserted, nil, &wd)
+	})
+
+	return wrap(err, "insert working day %s", wd.Date.Format("2006-01-02"))
+}
+
+// Delete removes a working day and its sessions from the database. The change is journaled.
+func (r *SqlRepo) Delete(wd WorkingDay) error {
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		before := &WorkingDay{}
+		if err := tx.Preload("Sessions", orderSessions).First(before, wd.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&WorkingDay{}, wd.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("working_day_id = ? AND deleted_at IS NULL", wd.ID).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+
+		return record(tx, Deleted, before, nil)
+	})
+
+	return wrap(err, "delete working day %s", wd.Date.Format("2006-01-02"))
 }
 
-// Insert adds a new working day to the database
-func (r *SqlRepo) Insert(wd WorkingDay) {
+// Overtime calculates the overtime in minutes across all sessions against the schedule. Running sessions count up to now.
+func (r *SqlRepo) Overtime() (int, error) {
 
-	tx := r.db.Create(&wd)
+	var workingDays []WorkingDay
+	tx := r.db.Preload("Sessions", orderSessions).Find(&workingDays)
 	if tx.Error != nil {
-		jww.ERROR.Fatal(tx.Error)
+		return 0, wrap(tx.Error, "load working days")
+	}
+
+	schedule, err := r.Schedule()
+	if err != nil {
+		return 0, err
 	}
+
+	overtime := schedule.Overtime(workingDays)
+	return int(overtime.Round(time.Minute).Minutes()), nil
 }
 
-// Delete removes a working day from the database
-func (r *SqlRepo) Delete(wd WorkingDay) {
-	tx := r.db.Delete(&wd, wd.ID)
+// ListRange loads all working days from the calendar day of start until the one of end ordered by date descending
+func (r *SqlRepo) ListRange(start *time.Time, end *time.Time) ([]WorkingDay, error) {
+	var workingDays []WorkingDay
+
+	s, _ := startEnd(start)
+	_, e := startEnd(end)
+	tx := r.db.Preload("Sessions", orderSessions).Where("date BETWEEN ? and ?", s, e).Order("date DESC").Find(&workingDays)
+
 	if tx.Error != nil {
-		jww.ERROR.Fatal(tx.Error)
+		return nil, wrap(tx.Error, "list working days")
 	}
 
-	rows := tx.RowsAffected
-	if rows != 1 {
-		jww.ERROR.Fatalf("Delete %d rows - expected 1 row", rows)
+	return workingDays, nil
+}
+
+// Schedule loads all targets
+func (r *SqlRepo) Schedule() (Schedule, error) {
+	var targets []Target
+
+	tx := r.db.Order("valid_from").Find(&targets)
+	if tx.Error != nil {
+		return Schedule{}, wrap(tx.Error, "load targets")
 	}
+
+	return NewSchedule(targets, r.DefaultTarget), nil
 }
 
-// Overtime calculates the overtime in minutes
-func (r *SqlRepo) Overtime() int {
+// SetTarget stores a target. A target valid from the same date is replaced.
+func (r *SqlRepo) SetTarget(t Target) error {
+	t.ValidFrom = dayOf(t.ValidFrom)
+
+	var existing Target
+	tx := r.db.Where("valid_from = ?", t.ValidFrom).Limit(1).Find(&existing)
+	if tx.Error != nil {
+		return wrap(tx.Error, "load target valid from %s", t.ValidFrom.Format("2006-01-02"))
+	}
+	if tx.RowsAffected == 1 {
+		t.Model = existing.Model
+	}
+
+	tx = r.db.Save(&t)
+	return wrap(tx.Error, "save target valid from %s", t.ValidFrom.Format("2006-01-02"))
+}
 
-	var overtime = -1
-	overtStmt := `
-	SELECT SUM((strftime('%s', end) - strftime('%s', start) - break_in_m * 60) / 60)
-	 - (COUNT(*) * 8 * 60) AS overtime
-	FROM working_days WHERE deleted_at IS NULL;
-	`
+// LoadProject finds a project by its name
+func (r *SqlRepo) LoadProject(name string) (*Project, error) {
+	p := &Project{}
 
-	tx := r.db.Raw(overtStmt).Scan(&overtime)
+	tx := r.db.Where("name = ?", name).First(p)
 	if tx.Error != nil {
-		jww.ERROR.Fatal(tx.Error)
+		return nil, wrap(tx.Error, "load project '%s'", name)
 	}
+	return p, nil
+}
 
-	if overtime == -1 {
-		jww.ERROR.Fatal("Could not calculate overtime")
+// SaveProject adds a new project or updates an existing one. Project names are unique.
+func (r *SqlRepo) SaveProject(p Project) error {
+	var count int64
+	tx := r.db.Model(&Project{}).Where("name = ? AND id <> ?", p.Name, p.ID).Count(&count)
+	if tx.Error != nil {
+		return wrap(tx.Error, "save project '%s'", p.Name)
 	}
-	return overtime
+	if count > 0 {
+		return wrap(ErrConflict, "save project '%s'", p.Name)
+	}
+
+	tx = r.db.Save(&p)
+	return wrap(tx.Error, "save project '%s'", p.Name)
 }
 
-func (r *SqlRepo) ListRange(start *time.Time, end *time.Time) ([]WorkingDay, error) {
-	var workingDays []WorkingDay
+// Projects loads all projects including the archived ones ordered by name
+func (r *SqlRepo) Projects() ([]Project, error) {
+	var projects []Project
+
+	tx := r.db.Order("name").Find(&projects)
+	if tx.Error != nil {
+		return nil, wrap(tx.Error, "load projects")
+	}
+	return projects, nil
+}
+
+// Transaction runs fc in a database transaction. All changes of fc are rolled back when it returns an error.
+func (r *SqlRepo) Transaction(fc func(tx Repo) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fc(&SqlRepo{db: tx, DefaultTarget: r.DefaultTarget})
+	})
+}
+
+// Dump reads every record of every table including the soft deleted ones
+func (r *SqlRepo) Dump() (ArchiveTables, error) {
+	var tables ArchiveTables
+
+	for _, dest := range []interface{}{&tables.WorkingDays, &tables.Sessions, &tables.Targets, &tables.Projects, &tables.Changes, &tables.AuditEntries} {
+		if err := r.db.Unscoped().Order("id").Find(dest).Error; err != nil {
+			return ArchiveTables{}, wrap(err, "dump database")
+		}
+	}
+	return tables, nil
+}
 
-	tx := r.db.Where("start BETWEEN ? and ?", start, end).Order("start DESC").Find(&workingDays)
+// Restore inserts all records of the tables unchanged. The database must not contain any records.
+func (r *SqlRepo) Restore(tables ArchiveTables) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&WorkingDay{}, &Session{}, &Target{}, &Project{}, &Change{}, &AuditEntry{}} {
+			var count int64
+			if err := tx.Unscoped().Model(model).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrNotEmpty
+			}
+		}
 
+		if len(tables.WorkingDays) > 0 {
+			if err := tx.Omit("Sessions").Create(&tables.WorkingDays).Error; err != nil {
+				return err
+			}
+		}
+		if len(tables.Sessions) > 0 {
+			if err := tx.Create(&tables.Sessions).Error; err != nil {
+				return err
+			}
+		}
+		if len(tables.Targets) > 0 {
+			if err := tx.Create(&tables.Targets).Error; err != nil {
+				return err
+			}
+		}
+		if len(tables.Projects) > 0 {
+			if err := tx.Create(&tables.Projects).Error; err != nil {
+				return err
+			}
+		}
+		if len(tables.Changes) > 0 {
+			if err := tx.Create(&tables.Changes).Error; err != nil {
+				return err
+			}
+		}
+		if len(tables.AuditEntries) > 0 {
+			if err := tx.Create(&tables.AuditEntries).Error; err != nil {
+				return err
+			}
+		}
+		return resetSequences(tx)
+	})
+
+	return wrap(err, "restore database")
+}
+
+// resetSequences continues the ids of PostgreSQL tables after the highest stored one. Other databases derive the
+// next id from the stored ones.
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range []string{"working_days", "sessions", "targets", "projects", "changes", "audit_entries"} {
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s", table, table)
+		if err := tx.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orderSessions(db *gorm.DB) *gorm.DB {
+	return db.Order("start")
+}
+
+// migrateSessions moves the start and end of working days, which were stored before sessions existed, into sessions.
+func migrateSessions(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&WorkingDay{}, "start") {
+		return nil
+	}
+
+	type legacyDay struct {
+		ID    uint
+		Start time.Time
+		End   *time.Time
+	}
+
+	var legacyDays []legacyDay
+	tx := db.Raw("SELECT id, start, `end` FROM working_days WHERE start IS NOT NULL").Scan(&legacyDays)
 	if tx.Error != nil {
-		return nil, tx.Error
+		return tx.Error
 	}
 
-	return workingDays, nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, ld := range legacyDays {
+			date := dayOf(ld.Start)
+			if err := tx.Exec("UPDATE working_days SET date = ?, start = NULL, `end` = NULL WHERE id = ?", date, ld.ID).Error; err != nil {
+				return err
+			}
+			session := Session{WorkingDayID: ld.ID, Start: ld.Start, End: ld.End}
+			if err := tx.Create(&session).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func startEnd(d *time.Time) (time.Time, time.Time) {
-	s := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Now().Location())
-	e := time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, 0, time.Now().Location())
-	return s, e
+// repairOvernight moves the end of sessions, which lie before their start, to the following day. Such sessions
+// were stored as night shifts before sessions could span midnight and reduced the overtime.
+func repairOvernight(db *gorm.DB) error {
+	var sessions []Session
+	end, start := clause.Column{Name: "end"}, clause.Column{Name: "start"}
+	if err := db.Where("? IS NOT NULL AND ? < ?", end, end, start).Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range sessions {
+			if !s.End.Before(s.Start) {
+				continue
+			}
+			end := s.End.AddDate(0, 0, 1).UTC()
+			if err := tx.Model(&Session{}).Where("id = ?", s.ID).UpdateColumn("end", end).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -1,9 +1,14 @@
 package db
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 const dbName = "test.db"
@@ -16,28 +21,28 @@ func TestInsertAndLoad(t *testing.T) {
 
 	defer os.Remove(dbName)
 
-	repo := NewRepo(dbName)
+	repo := newTestRepo(t)
 
 	start := time.Date(2020, 10, 8, 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(2020, 10, 8, 16, 20, 00, 000, time.Now().Location())
-	wd := WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
-
-	repo.Insert(wd)
+	wd := newWorkingDay(start, end, 30, "With space")
 
-	wdFromDb := repo.LoadDay(&start)
+	if err := repo.Insert(wd); err != nil {
+		t.Fatal(err)
+	}
 
-	if wdFromDb == nil {
-		t.Error("Could not load working day from DB")
+	wdFromDb, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatalf("Could not load working day from DB: %s", err)
 	}
 
-	if wd.Start == wdFromDb.Start && wd.Brk == wdFromDb.Brk && wd.Note == wdFromDb.Note {
+	if !wd.Start().Equal(wdFromDb.Start()) || wd.Brk != wdFromDb.Brk || wd.Note != wdFromDb.Note {
 		t.Error("Working days do not match")
 	}
 
 	past := time.Date(2019, 4, 8, 8, 50, 00, 000, time.Now().Location())
-	wdFromDb = repo.LoadDay(&past)
-	if wdFromDb != nil {
-		t.Error("Loaded a non matching entry")
+	if _, err = repo.LoadDay(&past); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found but got %v", err)
 	}
 }
 
@@ -45,28 +50,34 @@ func TestUpdateAndLoad(t *testing.T) {
 
 	defer os.Remove(dbName)
 
-	repo := NewRepo(dbName)
+	repo := newTestRepo(t)
 
 	start := time.Date(2018, 10, 8, 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(2018, 10, 8, 16, 20, 00, 000, time.Now().Location())
-	wd := WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
-
-	repo.Insert(wd)
+	if err := repo.Insert(newWorkingDay(start, end, 30, "With space")); err != nil {
+		t.Fatal(err)
+	}
 
+	wd, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatal(err)
+	}
 	wd.Brk = 45
 	wd.Note = "NotSpace"
-	wd.Start = time.Date(2018, 10, 8, 7, 20, 00, 000, time.Now().Location())
-	wd.End = time.Date(2018, 10, 8, 17, 00, 00, 000, time.Now().Location())
+	wd.Sessions[0].Start = time.Date(2018, 10, 8, 7, 20, 00, 000, time.Now().Location())
+	newEnd := time.Date(2018, 10, 8, 17, 00, 00, 000, time.Now().Location())
+	wd.Sessions[0].End = &newEnd
 
-	repo.UpdateDay(wd)
-
-	wdFromDb := repo.LoadDay(&start)
+	if err = repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
 
-	if wdFromDb == nil {
-		t.Error("Could not load working day from DB")
+	wdFromDb, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatalf("Could not load working day from DB: %s", err)
 	}
 
-	if wd.Start == wdFromDb.Start && wd.Brk == wdFromDb.Brk && wd.Note == wdFromDb.Note && wd.Brk == 45 && wd.Note == "NotSpace" {
+	if !wd.Start().Equal(wdFromDb.Start()) || wdFromDb.Brk != 45 || wdFromDb.Note != "NotSpace" {
 		t.Error("Working days do not match")
 	}
 }
@@ -75,26 +86,77 @@ func TestDelete(t *testing.T) {
 
 	defer os.Remove(dbName)
 
-	repo := NewRepo(dbName)
+	repo := newTestRepo(t)
 
 	start := time.Date(2020, 10, 8, 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(2020, 10, 8, 16, 20, 00, 000, time.Now().Location())
-	wd := WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
+	if err := repo.Insert(newWorkingDay(start, end, 30, "With space")); err != nil {
+		t.Fatal(err)
+	}
 
-	repo.Insert(wd)
+	wdFromDb, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatalf("Could not load working day from DB: %s", err)
+	}
 
-	wdFromDb := repo.LoadDay(&start)
+	if err = repo.Delete(*wdFromDb); err != nil {
+		t.Fatal(err)
+	}
 
-	if wdFromDb == nil {
-		t.Error("Could not load working day from DB")
+	if _, err = repo.LoadDay(&start); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Did not delete working day")
 	}
+}
+
+func TestSqlRepo_Errors(t *testing.T) {
 
-	repo.Delete(*wdFromDb)
+	defer os.Remove(dbName)
 
-	wdFromDb = repo.LoadDay(&start)
+	repo := newTestRepo(t)
 
-	if wdFromDb != nil {
-		t.Fatal("Did not delete working day")
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	wd := newWorkingDay(start, start.Add(time.Hour), 0, "")
+	if err := repo.Insert(wd); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Insert(wd); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected conflict for second day on the same date but got %v", err)
+	}
+
+	if err := repo.UpdateDay(wd); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found for update of unsaved day but got %v", err)
+	}
+
+	loaded, _ := repo.LoadDay(&start)
+	if err := repo.Delete(*loaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(*loaded); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found for deleting twice but got %v", err)
+	}
+
+	if _, err := repo.LoadRunning(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found for missing running session but got %v", err)
+	}
+
+	if err := repo.SaveProject(Project{Name: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveProject(Project{Name: "acme"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected conflict for duplicated project but got %v", err)
+	}
+}
+
+func TestNewRepo_Corrupt(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	if err := ioutil.WriteFile(dbName, []byte("this is not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewRepo(dbName); err == nil {
+		t.Fatal("Opened corrupt database")
 	}
 }
 
@@ -102,16 +164,359 @@ func TestSqlRepo_Overtime(t *testing.T) {
 
 	defer os.Remove(dbName)
 
-	repo := NewRepo(dbName)
+	repo := newTestRepo(t)
 
 	start := time.Date(2020, 10, 8, 7, 50, 00, 000, time.Now().Location())
 	end := time.Date(2020, 10, 8, 16, 50, 00, 000, time.Now().Location())
-	wd := WorkingDay{Start: start, End: end, Brk: 30, Note: "With space"}
+	if err := repo.Insert(newWorkingDay(start, end, 30, "With space")); err != nil {
+		t.Fatal(err)
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 30 {
+		t.Fatalf("Expected '%d' but got '%d'", 30, overtime)
+	}
+}
+
+func TestSqlRepo_OvertimeWithSessions(t *testing.T) {
+
+	defer os.Remove(dbName)
 
-	repo.Insert(wd)
-	overtime := repo.Overtime()
+	repo := newTestRepo(t)
 
-	if overtime != 30 {
+	start := time.Date(2020, 10, 8, 7, 0, 00, 000, time.Now().Location())
+	end := time.Date(2020, 10, 8, 11, 0, 00, 000, time.Now().Location())
+	if err := repo.Insert(newWorkingDay(start, end, 30, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := repo.LoadDay(&start)
+	afternoonEnd := time.Date(2020, 10, 8, 18, 0, 00, 000, time.Now().Location())
+	wd.Sessions = append(wd.Sessions, Session{
+		Start: time.Date(2020, 10, 8, 13, 0, 00, 000, time.Now().Location()),
+		End:   &afternoonEnd,
+	})
+	if err := repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ = repo.LoadDay(&start)
+	if len(wd.Sessions) != 2 {
+		t.Fatalf("Expected 2 sessions but got %d", len(wd.Sessions))
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 30 {
 		t.Fatalf("Expected '%d' but got '%d'", 30, overtime)
 	}
+
+	// Removing a session from a day removes it from the database
+	wd.Sessions = wd.Sessions[1:]
+	if err := repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ = repo.LoadDay(&start)
+	if len(wd.Sessions) != 1 || wd.Start().Hour() != 13 {
+		t.Fatalf("Expected only the afternoon session but got %s", wd)
+	}
+}
+
+func TestSqlRepo_NightShift(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 22, 0, 00, 000, time.Now().Location())
+	end := time.Date(2020, 10, 9, 6, 0, 00, 000, time.Now().Location())
+	if err := repo.Insert(newWorkingDay(start, end, 0, "night")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shift belongs to the day it started on
+	if overtime := overtimeOf(t, repo); overtime != 0 {
+		t.Fatalf("Expected '%d' but got '%d'", 0, overtime)
+	}
+	if _, err := repo.LoadDay(&end); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected no working day on the end of the shift but got %v", err)
+	}
+	days, err := repo.ListRange(&end, &end)
+	if err != nil || len(days) != 0 {
+		t.Fatalf("Expected no working day on the end of the shift but got %v (%v)", days, err)
+	}
+	day := dayOf(start)
+	days, err = repo.ListRange(&day, &end)
+	if err != nil || len(days) != 1 || days[0].Worked() != 8*time.Hour || !days[0].Sessions[0].Overnight() {
+		t.Fatalf("Expected the night shift but got %v (%v)", days, err)
+	}
+
+	// Shifts stored with an end before their start by earlier versions are repaired
+	defer os.Remove(dbName + ".v0.bak")
+	wd, _ := repo.LoadDay(&start)
+	if err = repo.db.Exec("UPDATE sessions SET `end` = ? WHERE id = ?", end.AddDate(0, 0, -1), wd.Sessions[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.db.Exec("DROP TABLE schema_version").Error; err != nil {
+		t.Fatal(err)
+	}
+	repo = newTestRepo(t)
+	wd, _ = repo.LoadDay(&start)
+	if !wd.End().Equal(end) {
+		t.Fatalf("Expected the end to be moved to %s but got %s", end, wd.End())
+	}
+}
+
+func TestNewRepo_MigratesLegacyDays(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	legacy, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := []string{
+		"CREATE TABLE `working_days` (`id` integer,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime," +
+			"`start` datetime,`end` datetime,`break_in_m` integer,`note` text,PRIMARY KEY (`id`))",
+		"INSERT INTO `working_days` (`start`, `end`, `break_in_m`, `note`) VALUES (?, ?, 30, 'legacy')",
+	}
+	start := time.Date(2019, 5, 6, 8, 0, 00, 000, time.Now().Location())
+	end := time.Date(2019, 5, 6, 17, 0, 00, 000, time.Now().Location())
+	if err := legacy.Exec(stmts[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.Exec(stmts[1], start, end).Error; err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := legacy.DB()
+	sqlDB.Close()
+
+	defer os.Remove(dbName + ".v0.bak")
+	repo := newTestRepo(t)
+	wd, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatalf("Could not load migrated working day: %s", err)
+	}
+	if _, err = os.Stat(dbName + ".v0.bak"); err != nil {
+		t.Errorf("Expected a backup before the migration but got %v", err)
+	}
+	if len(wd.Sessions) != 1 || !wd.Start().Equal(start) || !wd.End().Equal(end) || wd.Note != "legacy" {
+		t.Fatalf("Legacy working day was not migrated correctly: %s", wd)
+	}
+
+	// Migration must not run twice
+	repo = newTestRepo(t)
+	wd, _ = repo.LoadDay(&start)
+	if len(wd.Sessions) != 1 {
+		t.Fatalf("Expected 1 session after second start but got %d", len(wd.Sessions))
+	}
+}
+
+// newTestRepo opens the test database
+func newTestRepo(t *testing.T) *SqlRepo {
+	repo, err := NewRepo(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// overtimeOf calculates the overtime of the repo
+func overtimeOf(t *testing.T, repo Repo) int {
+	overtime, err := repo.Overtime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return overtime
+}
+
+// newWorkingDay creates a working day with a single session
+func newWorkingDay(start time.Time, end time.Time, brk int, note string) WorkingDay {
+	return WorkingDay{Date: dayOf(start), Sessions: []Session{{Start: start, End: &end}}, Brk: brk, Note: note}
+}
+
+func TestSqlRepo_LoadRunning(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	if _, err := repo.LoadRunning(); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Found running session in empty database")
+	}
+
+	start := time.Now().Add(-2 * time.Hour)
+	if err := repo.Insert(WorkingDay{Date: dayOf(start), Sessions: []Session{{Start: start}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := repo.LoadRunning()
+	if err != nil || wd.Running() == nil {
+		t.Fatal("Could not load running session")
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 120-8*60 {
+		t.Fatalf("Expected running session to count up to now but got '%d'", overtime)
+	}
+
+	end := time.Now()
+	wd.Running().End = &end
+	if err = repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.LoadRunning(); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Found running session after it was ended")
+	}
+}
+
+func TestSqlRepo_OvertimeWithSchedule(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	// Thursday and Friday with 8h each
+	for _, day := range []int{8, 9} {
+		start := time.Date(2020, 10, day, 8, 0, 00, 000, time.Now().Location())
+		end := time.Date(2020, 10, day, 16, 0, 00, 000, time.Now().Location())
+		if err := repo.Insert(newWorkingDay(start, end, 0, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 0 {
+		t.Fatalf("Expected '%d' but got '%d'", 0, overtime)
+	}
+
+	// Part-time from friday on: 6h from monday to thursday, friday is free
+	partTime := Target{ValidFrom: time.Date(2020, 10, 9, 0, 0, 0, 0, time.Now().Location())}
+	for _, wd := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday} {
+		partTime.Set(wd, 6*time.Hour)
+	}
+	if err := repo.SetTarget(partTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 8*60 {
+		t.Fatalf("Expected '%d' but got '%d'", 8*60, overtime)
+	}
+
+	// Replacing the target of the same date
+	partTime.Set(time.Friday, 4*time.Hour)
+	if err := repo.SetTarget(partTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if schedule, _ := repo.Schedule(); len(schedule.Targets) != 1 {
+		t.Fatalf("Expected target to be replaced but got %d targets", len(schedule.Targets))
+	}
+	if overtime := overtimeOf(t, repo); overtime != 4*60 {
+		t.Fatalf("Expected '%d' but got '%d'", 4*60, overtime)
+	}
+}
+
+func TestSqlRepo_OvertimeWithDaysOff(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	end := time.Date(2020, 10, 8, 18, 0, 00, 000, time.Now().Location())
+	if err := repo.Insert(newWorkingDay(start, end, 0, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, dt := range []DayType{Vacation, Sick, Holiday} {
+		if err := repo.Insert(WorkingDay{Date: dayOf(start.AddDate(0, 0, i+1)), Type: dt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 2*60 {
+		t.Fatalf("Expected '%d' but got '%d'", 2*60, overtime)
+	}
+
+	if err := repo.Insert(WorkingDay{Date: dayOf(start.AddDate(0, 0, 4)), Type: CompTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != -6*60 {
+		t.Fatalf("Expected '%d' but got '%d'", -6*60, overtime)
+	}
+
+	wd, _ := repo.LoadDay(&start)
+	if wd.Kind() != Work {
+		t.Fatalf("Expected default type '%s' but got '%s'", Work, wd.Kind())
+	}
+}
+
+func TestSqlRepo_Projects(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	for _, name := range []string{"globex", "acme"} {
+		if err := repo.SaveProject(Project{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := repo.LoadProject("acme")
+	if err != nil {
+		t.Fatalf("Could not load project: %s", err)
+	}
+
+	p.Archived = true
+	if err = repo.SaveProject(*p); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, _ := repo.Projects()
+	if len(projects) != 2 || projects[0].Name != "acme" || !projects[0].Archived {
+		t.Fatalf("Unexpected projects %+v", projects)
+	}
+
+	if _, err = repo.LoadProject("initech"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Loaded unknown project")
+	}
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	wd := newWorkingDay(start, start.Add(time.Hour), 0, "")
+	wd.Sessions[0].Project = "globex"
+	wd.Sessions[0].Tags = JoinTags([]string{"bug", " call", "bug", ""})
+	if err = repo.Insert(wd); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := repo.LoadDay(&start)
+	s := loaded.Sessions[0]
+	if s.Project != "globex" || s.Tags != "bug,call" || !s.Matches("globex", "call") || s.Matches("acme", "") {
+		t.Fatalf("Unexpected session %s", s.String())
+	}
+}
+
+func TestSqlRepo_Transaction(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	err := repo.Transaction(func(tx Repo) error {
+		if err := tx.Insert(newWorkingDay(start, start.Add(time.Hour), 0, "")); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if _, loadErr := repo.LoadDay(&start); err == nil || !errors.Is(loadErr, ErrNotFound) {
+		t.Fatal("Transaction was not rolled back")
+	}
+
+	err = repo.Transaction(func(tx Repo) error {
+		return tx.Insert(newWorkingDay(start, start.Add(time.Hour), 0, ""))
+	})
+	if _, loadErr := repo.LoadDay(&start); err != nil || loadErr != nil {
+		t.Fatal("Transaction was not committed")
+	}
 }
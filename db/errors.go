@@ -0,0 +1,36 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write collides with an existing record
+	ErrConflict = errors.New("conflict")
+	// ErrNotEmpty is returned when an archive should be restored into a database with data
+	ErrNotEmpty = errors.New("database is not empty")
+	// ErrInvalidArchive is returned for archives which are damaged or not supported
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrInvalid is returned for working days with impossible data
+	ErrInvalid = errors.New("invalid working day")
+	// ErrInvalidBackup is returned for backups which are damaged or no timed database
+	ErrInvalidBackup = errors.New("invalid backup")
+	// ErrSchema is returned for schema versions which are not known
+	ErrSchema = errors.New("unsupported schema version")
+)
+
+// wrap adds context to an error of the database. A missing record becomes ErrNotFound.
+func wrap(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrNotFound
+	}
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileGenerator is the generator of archives written by the file backend
+const fileGenerator = "timed file backend"
+
+// FileRepo keeps the database in a JSON archive. The records are held in memory and the archive is rewritten after
+// every successful change. A missing archive is created by the first change.
+type FileRepo struct {
+	*SqlRepo
+	path string
+}
+
+func openFile(dsn string, defaultTarget time.Duration) (Repo, error) {
+	repo, err := NewFileRepo(pathOf(dsn))
+	if err != nil {
+		return nil, err
+	}
+	repo.DefaultTarget = defaultTarget
+	return repo, nil
+}
+
+// NewFileRepo loads the JSON archive at the path into a new repo
+func NewFileRepo(path string) (*FileRepo, error) {
+	memory, err := newMemoryRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FileRepo{SqlRepo: memory, path: path}, nil
+	}
+	if err != nil {
+		return nil, wrap(err, "open database '%s'", path)
+	}
+	defer f.Close()
+
+	archive, err := ReadArchive(f)
+	if err != nil {
+		return nil, wrap(err, "read database '%s'", path)
+	}
+	if err = memory.Restore(archive.Tables); err != nil {
+		return nil, wrap(err, "load database '%s'", path)
+	}
+	return &FileRepo{SqlRepo: memory, path: path}, nil
+}
+
+// Insert adds a new working day and writes the archive
+func (r *FileRepo) Insert(wd WorkingDay) error {
+	return r.save(r.SqlRepo.Insert(wd))
+}
+
+// UpdateDay updates a working day and writes the archive
+func (r *FileRepo) UpdateDay(wd WorkingDay) error {
+	return r.save(r.SqlRepo.UpdateDay(wd))
+}
+
+// Delete removes a working day and writes the archive
+func (r *FileRepo) Delete(wd WorkingDay) error {
+	return r.save(r.SqlRepo.Delete(wd))
+}
+
+// SetTarget stores a target and writes the archive
+func (r *FileRepo) SetTarget(t Target) error {
+	return r.save(r.SqlRepo.SetTarget(t))
+}
+
+// SaveProject stores a project and writes the archive
+func (r *FileRepo) SaveProject(p Project) error {
+	return r.save(r.SqlRepo.SaveProject(p))
+}
+
+// Transaction runs fc in a transaction and writes the archive once it is committed
+func (r *FileRepo) Transaction(fc func(tx Repo) error) error {
+	return r.save(r.SqlRepo.Transaction(fc))
+}
+
+// Restore inserts all records of the tables and writes the archive
+func (r *FileRepo) Restore(tables ArchiveTables) error {
+	return r.save(r.SqlRepo.Restore(tables))
+}
+
+// Undo reverts the latest change and writes the archive
+func (r *FileRepo) Undo() (*Change, error) {
+	c, err := r.SqlRepo.Undo()
+	return c, r.save(err)
+}
+
+// Redo applies the latest undone change again and writes the archive
+func (r *FileRepo) Redo() (*Change, error) {
+	c, err := r.SqlRepo.Redo()
+	return c, r.save(err)
+}
+
+// RestoreDeleted takes a working day out of the trash and writes the archive
+func (r *FileRepo) RestoreDeleted(id uint) (*WorkingDay, error) {
+	wd, err := r.SqlRepo.RestoreDeleted(id)
+	return wd, r.save(err)
+}
+
+// Purge removes deleted working days for good and writes the archive
+func (r *FileRepo) Purge(before time.Time) (int, error) {
+	n, err := r.SqlRepo.Purge(before)
+	return n, r.save(err)
+}
+
+// save writes the archive unless the change failed. The archive is replaced at once, so it is never half written.
+func (r *FileRepo) save(err error) error {
+	if err != nil {
+		return err
+	}
+
+	tables, err := r.SqlRepo.Dump()
+	if err != nil {
+		return err
+	}
+	archive, err := NewArchive(tables, fileGenerator)
+	if err != nil {
+		return wrap(err, "write database '%s'", r.path)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return wrap(err, "write database '%s'", r.path)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = archive.WriteJSON(tmp); err != nil {
+		tmp.Close()
+		return wrap(err, "write database '%s'", r.path)
+	}
+	if err = tmp.Close(); err != nil {
+		return wrap(err, "write database '%s'", r.path)
+	}
+	return wrap(os.Rename(tmp.Name(), r.path), "write database '%s'", r.path)
+}
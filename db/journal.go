@@ -0,0 +1,208 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChangeAction names the kind of modification of a working day
+type ChangeAction string
+
+const (
+	// Inserted means that a working day was added
+	Inserted ChangeAction = "insert"
+	// Updated means that a working day or its sessions were changed
+	Updated ChangeAction = "update"
+	// Deleted means that a working day was removed
+	Deleted ChangeAction = "delete"
+)
+
+// Change is an entry of the change journal. It keeps the state of a working day before and after a modification.
+type Change struct {
+	gorm.Model
+
+	Date   time.Time `gorm:"index"`
+	Action ChangeAction
+
+	// Before and After contain the working day with its sessions as JSON. They are empty when the day did not exist.
+	Before string
+	After  string
+
+	// Undone is set while the change is reverted
+	Undone bool `gorm:"index"`
+}
+
+// newChange creates a journal entry for the states of a working day. A nil state means that the day does not exist.
+func newChange(action ChangeAction, before *WorkingDay, after *WorkingDay) (Change, error) {
+	c := Change{Action: action}
+
+	for _, state := range []struct {
+		wd   *WorkingDay
+		dest *string
+	}{{before, &c.Before}, {after, &c.After}} {
+		if state.wd == nil {
+			continue
+		}
+		c.Date = state.wd.Date
+
+		content, err := json.Marshal(state.wd)
+		if err != nil {
+			return Change{}, err
+		}
+		*state.dest = string(content)
+	}
+
+	return c, nil
+}
+
+// Previous returns the working day before the change or nil when it did not exist
+func (c *Change) Previous() (*WorkingDay, error) {
+	return decodeState(c.Before)
+}
+
+// Next returns the working day after the change or nil when it was deleted
+func (c *Change) Next() (*WorkingDay, error) {
+	return decodeState(c.After)
+}
+
+func decodeState(state string) (*WorkingDay, error) {
+	if state == "" {
+		return nil, nil
+	}
+
+	wd := &WorkingDay{}
+	if err := json.Unmarshal([]byte(state), wd); err != nil {
+		return nil, err
+	}
+	return wd, nil
+}
+
+// record adds a change to the journal and to the audit table. Undone changes can not be redone after a new change.
+func record(tx *gorm.DB, action ChangeAction, before *WorkingDay, after *WorkingDay) error {
+	if err := audit(tx, action, before, after); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("undone = ?", true).Delete(&Change{}).Error; err != nil {
+		return err
+	}
+
+	c, err := newChange(action, before, after)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&c).Error
+}
+
+// applyState replaces the stored working day by the state. A nil state removes the current day.
+func applyState(tx *gorm.DB, current *WorkingDay, state *WorkingDay) error {
+	if state == nil {
+		if current == nil {
+			return nil
+		}
+		if err := tx.Delete(&WorkingDay{}, current.ID).Error; err != nil {
+			return err
+		}
+		return tx.Where("working_day_id = ? AND deleted_at IS NULL", current.ID).Delete(&Session{}).Error
+	}
+
+	// Deleted records are revived
+	wd := *state
+	wd.DeletedAt = gorm.DeletedAt{}
+	if err := tx.Unscoped().Omit("Sessions").Save(&wd).Error; err != nil {
+		return err
+	}
+
+	keep := make([]uint, 0, len(wd.Sessions))
+	for _, s := range wd.Sessions {
+		s.DeletedAt = gorm.DeletedAt{}
+		if err := tx.Unscoped().Save(&s).Error; err != nil {
+			return err
+		}
+		keep = append(keep, s.ID)
+	}
+
+	obsolete := tx.Where("working_day_id = ? AND deleted_at IS NULL", wd.ID)
+	if len(keep) > 0 {
+		obsolete = obsolete.Where("id NOT IN ?", keep)
+	}
+	return obsolete.Delete(&Session{}).Error
+}
+
+// History loads the latest changes of the journal starting with the newest one
+func (r *SqlRepo) History(limit int) ([]Change, error) {
+	var changes []Change
+
+	tx := r.db.Order("id DESC").Limit(limit).Find(&changes)
+	if tx.Error != nil {
+		return nil, wrap(tx.Error, "load history")
+	}
+	return changes, nil
+}
+
+// Undo reverts the latest change which is not undone yet
+func (r *SqlRepo) Undo() (*Change, error) {
+	c := &Change{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("undone = ?", false).Order("id DESC").First(c).Error; err != nil {
+			return err
+		}
+		if err := revert(tx, c, false); err != nil {
+			return err
+		}
+
+		c.Undone = true
+		return tx.Save(c).Error
+	})
+
+	if err != nil {
+		return nil, wrap(err, "undo")
+	}
+	return c, nil
+}
+
+// Redo applies the oldest undone change again
+func (r *SqlRepo) Redo() (*Change, error) {
+	c := &Change{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("undone = ?", true).Order("id").First(c).Error; err != nil {
+			return err
+		}
+		if err := revert(tx, c, true); err != nil {
+			return err
+		}
+
+		c.Undone = false
+		return tx.Save(c).Error
+	})
+
+	if err != nil {
+		return nil, wrap(err, "redo")
+	}
+	return c, nil
+}
+
+// revert restores the state before the change or the state after it when forward is set. The alteration is audited.
+func revert(tx *gorm.DB, c *Change, forward bool) error {
+	previous, err := c.Previous()
+	if err != nil {
+		return err
+	}
+	next, err := c.Next()
+	if err != nil {
+		return err
+	}
+
+	from, to, action := next, previous, Reverted
+	if forward {
+		from, to, action = previous, next, Reapplied
+	}
+
+	if err = applyState(tx, from, to); err != nil {
+		return err
+	}
+	return audit(tx, action, from, to)
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSqlRepo_UndoRedo(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	if _, err := repo.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected nothing to undo but got %v", err)
+	}
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	if err := repo.Insert(newWorkingDay(start, start.Add(8*time.Hour), 30, "original")); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := repo.LoadDay(&start)
+	wd.Sessions[0].Start = start.Add(10 * time.Hour)
+	wd.Note = "mistyped"
+	if err := repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(*wd); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repo.History(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Action != Deleted || history[2].Action != Inserted {
+		t.Fatalf("Unexpected history %+v", history)
+	}
+
+	// Undo the delete revives the mistyped day
+	if c, err := repo.Undo(); err != nil || c.Action != Deleted {
+		t.Fatalf("Could not undo delete: %v", err)
+	}
+	wd, err = repo.LoadDay(&start)
+	if err != nil || wd.Note != "mistyped" || len(wd.Sessions) != 1 {
+		t.Fatalf("Undo did not revive deleted day: %v %v", wd, err)
+	}
+
+	// Undo the update restores the original start
+	if _, err = repo.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ = repo.LoadDay(&start)
+	if wd.Note != "original" || !wd.Start().Equal(start) {
+		t.Fatalf("Undo did not restore the previous state: %s", wd)
+	}
+
+	// Redo applies the update again
+	if c, err := repo.Redo(); err != nil || c.Action != Updated {
+		t.Fatalf("Could not redo update: %v", err)
+	}
+	wd, _ = repo.LoadDay(&start)
+	if wd.Note != "mistyped" {
+		t.Fatalf("Redo did not apply the update: %s", wd)
+	}
+
+	// A new change discards the undone delete
+	wd.Note = "fixed"
+	if err = repo.UpdateDay(*wd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.Redo(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected nothing to redo but got %v", err)
+	}
+
+	// Undo everything removes the day
+	for i := 0; i < 3; i++ {
+		if _, err = repo.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = repo.LoadDay(&start); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Undo of insert did not remove the day: %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is a numbered step which changes the schema or the data of the database. Up applies the step in a
+// transaction together with recording its version, Down reverts it. Steps without Down cannot be rolled back.
+type Migration struct {
+	Version int
+	Name    string
+
+	up   func(tx *gorm.DB) error
+	down func(tx *gorm.DB) error
+	// drops tells that Down drops tables together with their data
+	drops bool
+}
+
+// Reversible reports whether the migration can be rolled back
+func (m Migration) Reversible() bool {
+	return m.down != nil
+}
+
+// Drops reports whether rolling back the migration drops tables together with their data
+func (m Migration) Drops() bool {
+	return m.drops
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d (%s)", m.Version, m.Name)
+}
+
+// MigrationStatus tells whether a migration was applied to the database
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is nil while the migration is pending
+	AppliedAt *time.Time
+}
+
+// schemaVersion records an applied migration
+type schemaVersion struct {
+	Version   int `gorm:"primarykey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName names the table of the applied migrations
+func (schemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Schema migrates a database between the versions of its schema
+type Schema struct {
+	db   *gorm.DB
+	name string
+	// file is the SQLite database which is copied before a migration. It is empty for other databases.
+	file string
+
+	// Backup is the copy of the database taken before the latest migration or rollback. It is empty when
+	// nothing was copied.
+	Backup string
+}
+
+// LatestVersion returns the version of the newest known migration
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// OpenSchema opens the schema of the SQLite or PostgreSQL database of the data source name without migrating it
+func OpenSchema(dsn string) (*Schema, error) {
+	switch scheme := schemeOf(dsn); scheme {
+	case "sqlite":
+		return openSchema(sqlite.Open(pathOf(dsn)), pathOf(dsn), pathOf(dsn))
+	case "postgres", "postgresql":
+		return openSchema(postgres.Open(dsn), redacted(dsn), "")
+	default:
+		return nil, fmt.Errorf("database backend '%s' has no versioned schema - it is migrated when opened", scheme)
+	}
+}
+
+// openSchema connects to the database of the dialector. The name describes the database in errors.
+func openSchema(dialector gorm.Dialector, name string, file string) (*Schema, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, wrap(err, "open database '%s'", name)
+	}
+	return &Schema{db: db, name: name, file: file}, nil
+}
+
+// Version returns the version of the newest applied migration. It is 0 for an empty database and for one of a
+// version of timed without versioned migrations.
+func (s *Schema) Version() (int, error) {
+	if !s.db.Migrator().HasTable(&schemaVersion{}) {
+		return 0, nil
+	}
+
+	var version int
+	err := s.db.Model(&schemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, wrap(err, "read schema version of database '%s'", s.name)
+}
+
+// Status lists all known migrations in order with the time they were applied
+func (s *Schema) Status() ([]MigrationStatus, error) {
+	applied := map[int]time.Time{}
+	if s.db.Migrator().HasTable(&schemaVersion{}) {
+		var versions []schemaVersion
+		if err := s.db.Find(&versions).Error; err != nil {
+			return nil, wrap(err, "read schema version of database '%s'", s.name)
+		}
+		for _, v := range versions {
+			applied[v.Version] = v.AppliedAt
+		}
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		ms := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			ms.AppliedAt = &at
+		}
+		status = append(status, ms)
+	}
+	return status, nil
+}
+
+// Migrate applies all pending migrations up to the target version in order. An existing SQLite database is copied
+// first. It returns the applied migrations.
+func (s *Schema) Migrate(target int) ([]Migration, error) {
+	current, err := s.check(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > current && m.Version <= target {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if err = s.backup(current); err != nil {
+		return nil, err
+	}
+	if err = s.db.AutoMigrate(&schemaVersion{}); err != nil {
+		return nil, wrap(err, "migrate database '%s'", s.name)
+	}
+
+	for i, m := range pending {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return pending[:i], wrap(err, "apply migration %s to database '%s'", m, s.name)
+		}
+	}
+	return pending, nil
+}
+
+// Rollback reverts the applied migrations above the target version starting with the newest one. Nothing is
+// reverted when one of them cannot be rolled back. An existing SQLite database is copied first. Tables of other
+// databases are only dropped when the rollback is forced. It returns the reverted migrations.
+func (s *Schema) Rollback(target int, force bool) ([]Migration, error) {
+	current, err := s.check(target)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.Rollbacks(target)
+	if err != nil || len(applied) == 0 {
+		return nil, err
+	}
+	for _, m := range applied {
+		if m.drops && s.file == "" && !force {
+			return nil, fmt.Errorf("rolling back migration %s drops the tables of database '%s' which cannot be copied before - force the rollback to go on", m, s.name)
+		}
+	}
+
+	if err = s.backup(current); err != nil {
+		return nil, err
+	}
+
+	for i, m := range applied {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaVersion{}, m.Version).Error
+		})
+		if err != nil {
+			return applied[:i], wrap(err, "roll back migration %s of database '%s'", m, s.name)
+		}
+	}
+	return applied, nil
+}
+
+// Rollbacks lists the applied migrations above the target version which Rollback reverts starting with the newest
+// one. It fails when one of them cannot be rolled back.
+func (s *Schema) Rollbacks(target int) ([]Migration, error) {
+	current, err := s.check(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= current && m.Version > target {
+			if !m.Reversible() {
+				return nil, fmt.Errorf("migration %s cannot be rolled back", m)
+			}
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// check returns the current version. It fails for unknown versions.
+func (s *Schema) check(target int) (int, error) {
+	current, err := s.Version()
+	if err != nil {
+		return 0, err
+	}
+	if current > LatestVersion() {
+		return 0, fmt.Errorf("%w: database '%s' has schema version %d but this timed only knows versions up to %d - update timed",
+			ErrSchema, s.name, current, LatestVersion())
+	}
+	if target < 0 || target > LatestVersion() {
+		return 0, fmt.Errorf("%w: version %d does not exist - use 0 to %d", ErrSchema, target, LatestVersion())
+	}
+	return current, nil
+}
+
+// backup copies a SQLite database with data before it is migrated. The copy is named after the version it has.
+func (s *Schema) backup(version int) error {
+	s.Backup = ""
+	if s.file == "" || !s.db.Migrator().HasTable(&WorkingDay{}) {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", s.file, version)
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := vacuumInto(s.db, backup); err != nil {
+		return wrap(err, "back up database '%s' before migrating", s.name)
+	}
+	s.Backup = backup
+	return nil
+}
+
+// close releases the connections to the database
+func (s *Schema) close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
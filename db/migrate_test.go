@@ -0,0 +1,123 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+)
+
+func TestSchema_MigrateAndRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timed-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "timed.db")
+
+	schema, err := OpenSchema("sqlite://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := schema.Version(); err != nil || version != 0 {
+		t.Fatalf("Expected version 0 of an empty database but got %d (%v)", version, err)
+	}
+
+	applied, err := schema.Migrate(LatestVersion())
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Expected all migrations to be applied but got %v (%v)", applied, err)
+	}
+	if schema.Backup != "" {
+		t.Errorf("Expected no backup of an empty database but got '%s'", schema.Backup)
+	}
+	if applied, err = schema.Migrate(LatestVersion()); err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing to migrate but got %v (%v)", applied, err)
+	}
+
+	repo, err := NewRepo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2021, 6, 1, 8, 0, 0, 0, time.Local)
+	if err = repo.Insert(newWorkingDay(day, day.Add(8*time.Hour), 0, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The audit trail may only be altered without the triggers
+	reverted, err := schema.Rollback(4, false)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 5 {
+		t.Fatalf("Expected migration 5 to be rolled back but got %v (%v)", reverted, err)
+	}
+	if err = repo.db.Exec("UPDATE audit_entries SET user = 'someone'").Error; err != nil {
+		t.Errorf("Expected the audit trail to be writable but got %v", err)
+	}
+	if _, err = os.Stat(schema.Backup); schema.Backup != path+".v5.bak" || err != nil {
+		t.Errorf("Expected a backup of version 5 but got '%s' (%v)", schema.Backup, err)
+	}
+
+	status, err := schema.Status()
+	if err != nil || len(status) != len(migrations) || status[3].AppliedAt == nil || status[4].AppliedAt != nil {
+		t.Errorf("Expected migrations up to 4 to be applied but got %v (%v)", status, err)
+	}
+
+	if _, err = schema.Rollback(0, false); err == nil || !strings.Contains(err.Error(), "cannot be rolled back") {
+		t.Errorf("Expected an irreversible migration but got %v", err)
+	}
+	if version, _ := schema.Version(); version != 4 {
+		t.Errorf("Expected version 4 after the failed rollback but got %d", version)
+	}
+
+	if applied, err = schema.Migrate(LatestVersion()); err != nil || len(applied) != 1 {
+		t.Errorf("Expected migration 5 to be applied again but got %v (%v)", applied, err)
+	}
+	if err = repo.db.Exec("UPDATE audit_entries SET user = 'someone else'").Error; err == nil {
+		t.Error("Expected the audit trail to be append-only again")
+	}
+
+	if _, err = schema.Migrate(LatestVersion() + 1); !errors.Is(err, ErrSchema) {
+		t.Errorf("Expected an unknown version but got %v", err)
+	}
+	if err = repo.db.Create(&schemaVersion{Version: LatestVersion() + 1, Name: "future"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewRepo(path); !errors.Is(err, ErrSchema) {
+		t.Errorf("Expected a database of a newer version to be refused but got %v", err)
+	}
+
+	if _, err = OpenSchema("memory://"); err == nil {
+		t.Error("Expected the in-memory database to have no versioned schema")
+	}
+}
+
+func TestSchema_RollbackDropsOnlyWhenForced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timed.db")
+
+	// Like a PostgreSQL database the schema has no file to copy
+	schema, err := openSchema(sqlite.Open(path), path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer schema.close()
+	if _, err = schema.Migrate(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = schema.Rollback(0, false); err == nil || !strings.Contains(err.Error(), "force the rollback") {
+		t.Fatalf("Expected the tables to be kept without a copy but got %v", err)
+	}
+	if !schema.db.Migrator().HasTable(&WorkingDay{}) {
+		t.Fatal("Dropped the tables without a copy")
+	}
+
+	if reverted, err := schema.Rollback(0, true); err != nil || len(reverted) != 1 || !reverted[0].Drops() {
+		t.Fatalf("Expected the forced rollback to drop the tables but got %v (%v)", reverted, err)
+	}
+	if schema.db.Migrator().HasTable(&WorkingDay{}) {
+		t.Error("Kept the tables of the forced rollback")
+	}
+}
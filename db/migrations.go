@@ -0,0 +1,130 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations contains every step of the schema in the order of their versions. Published steps must never change,
+// as databases record which ones they went through. Changes of the models need a new step at the end.
+var migrations = []Migration{
+	{Version: 1, Name: "create tables", up: createTablesV1, down: dropTablesV1, drops: true},
+	{Version: 2, Name: "move working times into sessions", up: sqliteOnly(migrateSessions)},
+	{Version: 3, Name: "store times in UTC", up: sqliteOnly(migrateUTC)},
+	{Version: 4, Name: "repair night shifts", up: repairOvernight, down: func(tx *gorm.DB) error {
+		// The repaired sessions are valid in every version
+		return nil
+	}},
+	{Version: 5, Name: "make audit trail append-only", up: execDialect(auditTriggers), down: execDialect(auditTriggerDrops)},
+}
+
+// sqliteOnly skips a migration of data which only earlier versions of timed wrote to SQLite databases
+func sqliteOnly(up func(tx *gorm.DB) error) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "sqlite" {
+			return nil
+		}
+		return up(tx)
+	}
+}
+
+// execDialect runs the statements of the dialect of the database
+func execDialect(statements map[string][]string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements[tx.Dialector.Name()] {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// =====================
+// ===== VERSION 1 =====
+// =====================
+
+// The models of version 1 are frozen copies, so the step creates the same tables after the models changed.
+// Tables of databases from before versioned migrations get the missing columns.
+
+type workingDayV1 struct {
+	gorm.Model
+
+	Date     time.Time   `gorm:"index"`
+	Type     string      `gorm:"default:work"`
+	Sessions []sessionV1 `gorm:"foreignKey:WorkingDayID"`
+
+	Brk  int `gorm:"column:break_in_m"`
+	Note string
+}
+
+type sessionV1 struct {
+	gorm.Model
+
+	WorkingDayID uint `gorm:"index"`
+
+	Start    time.Time
+	End      *time.Time
+	Project  string `gorm:"index"`
+	Tags     string
+	TimeZone string
+}
+
+type targetV1 struct {
+	gorm.Model
+
+	ValidFrom time.Time `gorm:"index"`
+
+	Monday    int `gorm:"column:monday_in_m"`
+	Tuesday   int `gorm:"column:tuesday_in_m"`
+	Wednesday int `gorm:"column:wednesday_in_m"`
+	Thursday  int `gorm:"column:thursday_in_m"`
+	Friday    int `gorm:"column:friday_in_m"`
+	Saturday  int `gorm:"column:saturday_in_m"`
+	Sunday    int `gorm:"column:sunday_in_m"`
+}
+
+type projectV1 struct {
+	gorm.Model
+
+	Name     string `gorm:"uniqueIndex"`
+	Archived bool
+}
+
+type changeV1 struct {
+	gorm.Model
+
+	Date   time.Time `gorm:"index"`
+	Action string
+	Before string
+	After  string
+	Undone bool `gorm:"index"`
+}
+
+type auditEntryV1 struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	WorkingDayID uint      `gorm:"index"`
+	Date         time.Time `gorm:"index"`
+	Action       string
+	User         string
+	OldValue     string
+	NewValue     string
+}
+
+func (workingDayV1) TableName() string { return "working_days" }
+func (sessionV1) TableName() string    { return "sessions" }
+func (targetV1) TableName() string     { return "targets" }
+func (projectV1) TableName() string    { return "projects" }
+func (changeV1) TableName() string     { return "changes" }
+func (auditEntryV1) TableName() string { return "audit_entries" }
+
+func createTablesV1(tx *gorm.DB) error {
+	return tx.AutoMigrate(&workingDayV1{}, &sessionV1{}, &targetV1{}, &projectV1{}, &changeV1{}, &auditEntryV1{})
+}
+
+func dropTablesV1(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&auditEntryV1{}, &changeV1{}, &projectV1{}, &targetV1{}, &sessionV1{}, &workingDayV1{})
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Project groups sessions, e.g. by the client the work was done for
+type Project struct {
+	gorm.Model
+
+	Name     string `gorm:"uniqueIndex"`
+	Archived bool
+}
+
+// JoinTags converts tags into their stored form. Empty and duplicated tags are dropped.
+func JoinTags(tags []string) string {
+	unique := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+
+	return strings.Join(unique, ",")
+}
+
+// TagList returns the tags of the session
+func (s *Session) TagList() []string {
+	if s.Tags == "" {
+		return nil
+	}
+	return strings.Split(s.Tags, ",")
+}
+
+// HasTag reports whether the session is tagged with tag
+func (s *Session) HasTag(tag string) bool {
+	for _, t := range s.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the session belongs to the project and has the tag. Empty values match every session.
+func (s *Session) Matches(project string, tag string) bool {
+	return (project == "" || s.Project == project) && (tag == "" || s.HasTag(tag))
+}
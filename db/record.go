@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// DayRecord is the stable machine readable form of a working day
+type DayRecord struct {
+	Date          string          `json:"date"`
+	Type          DayType         `json:"type"`
+	Sessions      []SessionRecord `json:"sessions"`
+	BreakMinutes  int             `json:"break_minutes"`
+	WorkedMinutes int             `json:"worked_minutes"`
+	TargetMinutes int             `json:"target_minutes"`
+	Note          string          `json:"note"`
+}
+
+// SessionRecord is the stable machine readable form of a session. End is null while the session is running.
+type SessionRecord struct {
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end"`
+	Project string     `json:"project"`
+	Tags    []string   `json:"tags"`
+}
+
+// ToRecord converts the working day into its record. The target is the time which should be worked on that day.
+func (wd *WorkingDay) ToRecord(target time.Duration) DayRecord {
+	sessions := make([]SessionRecord, 0, len(wd.Sessions))
+	for _, s := range wd.Sessions {
+		tags := s.TagList()
+		if tags == nil {
+			tags = []string{}
+		}
+		sessions = append(sessions, SessionRecord{Start: s.Start, End: s.End, Project: s.Project, Tags: tags})
+	}
+
+	return DayRecord{
+		Date:          wd.Date.Format("2006-01-02"),
+		Type:          wd.Kind(),
+		Sessions:      sessions,
+		BreakMinutes:  wd.Brk,
+		WorkedMinutes: int(wd.Worked().Round(time.Minute).Minutes()),
+		TargetMinutes: int(target.Minutes()),
+		Note:          wd.Note,
+	}
+}
+
+// FromRecord converts a record into a working day. Worked and target minutes are derived values and ignored.
+func FromRecord(r DayRecord) (WorkingDay, error) {
+	date, err := time.ParseInLocation("2006-01-02", r.Date, time.Now().Location())
+	if err != nil {
+		return WorkingDay{}, err
+	}
+
+	dayType := r.Type
+	if dayType == "" {
+		dayType = Work
+	}
+	if _, err = ParseDayType(string(dayType)); err != nil {
+		return WorkingDay{}, err
+	}
+
+	sessions := make([]Session, 0, len(r.Sessions))
+	for _, s := range r.Sessions {
+		if s.Start.IsZero() {
+			return WorkingDay{}, fmt.Errorf("session of %s has no start", r.Date)
+		}
+		sessions = append(sessions, Session{Start: s.Start, End: s.End, Project: s.Project, Tags: JoinTags(s.Tags)})
+	}
+
+	return WorkingDay{Date: date, Type: dayType, Sessions: sessions, Brk: r.BreakMinutes, Note: r.Note}, nil
+}
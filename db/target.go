@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// DefaultTarget is the daily target which applies as long as no target was defined and nothing else was configured
+const DefaultTarget = 8 * time.Hour
+
+// Target defines how long should be worked on each weekday starting from a date
+type Target struct {
+	gorm.Model
+
+	ValidFrom time.Time `gorm:"index"`
+
+	Monday    int `gorm:"column:monday_in_m"`
+	Tuesday   int `gorm:"column:tuesday_in_m"`
+	Wednesday int `gorm:"column:wednesday_in_m"`
+	Thursday  int `gorm:"column:thursday_in_m"`
+	Friday    int `gorm:"column:friday_in_m"`
+	Saturday  int `gorm:"column:saturday_in_m"`
+	Sunday    int `gorm:"column:sunday_in_m"`
+}
+
+// Of returns the target for a weekday
+func (t *Target) Of(weekday time.Weekday) time.Duration {
+	minutes := [...]int{t.Sunday, t.Monday, t.Tuesday, t.Wednesday, t.Thursday, t.Friday, t.Saturday}
+	return time.Duration(minutes[weekday]) * time.Minute
+}
+
+// Set changes the target for a weekday
+func (t *Target) Set(weekday time.Weekday, target time.Duration) {
+	minutes := [...]*int{&t.Sunday, &t.Monday, &t.Tuesday, &t.Wednesday, &t.Thursday, &t.Friday, &t.Saturday}
+	*minutes[weekday] = int(target.Minutes())
+}
+
+func (t *Target) String() string {
+	return fmt.Sprintf("From %s: Mo %s, Tu %s, We %s, Th %s, Fr %s, Sa %s, Su %s",
+		t.ValidFrom.Format("2006-01-02"), t.Of(time.Monday), t.Of(time.Tuesday), t.Of(time.Wednesday),
+		t.Of(time.Thursday), t.Of(time.Friday), t.Of(time.Saturday), t.Of(time.Sunday))
+}
+
+// Schedule contains all targets ordered by the date they become valid
+type Schedule struct {
+	Targets []Target
+	// Default applies to every day before the first target
+	Default time.Duration
+}
+
+// NewSchedule creates a schedule from targets in any order
+func NewSchedule(targets []Target, defaultTarget time.Duration) Schedule {
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].ValidFrom.Before(targets[j].ValidFrom)
+	})
+	return Schedule{Targets: targets, Default: defaultTarget}
+}
+
+// TargetOf returns how long should be worked on a date
+func (s Schedule) TargetOf(date time.Time) time.Duration {
+	day := dayOf(date)
+	for i := len(s.Targets) - 1; i >= 0; i-- {
+		if !dayOf(s.Targets[i].ValidFrom).After(day) {
+			return s.Targets[i].Of(day.Weekday())
+		}
+	}
+	return s.Default
+}
+
+// TargetOfDay returns how long should be worked on a day considering its type.
+// Vacation, sick days and public holidays meet the target, so nothing has to be worked.
+func (s Schedule) TargetOfDay(wd *WorkingDay) time.Duration {
+	if wd.Kind().MeetsTarget() {
+		return 0
+	}
+	return s.TargetOf(wd.Date)
+}
+
+// Overtime sums up the difference between worked time and target of every working day
+func (s Schedule) Overtime(workingDays []WorkingDay) time.Duration {
+	var overtime time.Duration
+	for i := range workingDays {
+		overtime += workingDays[i].Worked() - s.TargetOfDay(&workingDays[i])
+	}
+	return overtime
+}
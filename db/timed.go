@@ -4,26 +4,187 @@ import (
 	"fmt"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
+// DayType distinguishes working days from days off
+type DayType string
+
+const (
+	// Work is a regular working day
+	Work DayType = "work"
+	// Vacation is a day off which counts against the vacation allowance
+	Vacation DayType = "vacation"
+	// Sick is a day off because of sickness
+	Sick DayType = "sick"
+	// Holiday is a public holiday
+	Holiday DayType = "holiday"
+	// CompTime is a day off which consumes overtime
+	CompTime DayType = "comp-time"
+)
+
+// DayTypes contains all known day types
+var DayTypes = []DayType{Work, Vacation, Sick, Holiday, CompTime}
+
+// ParseDayType converts a name into a day type
+func ParseDayType(name string) (DayType, error) {
+	for _, dt := range DayTypes {
+		if string(dt) == name {
+			return dt, nil
+		}
+	}
+	return "", fmt.Errorf("unknown day type '%s'", name)
+}
+
+// MeetsTarget reports whether the target of a day of this type is met without working
+func (dt DayType) MeetsTarget() bool {
+	return dt == Vacation || dt == Sick || dt == Holiday
+}
+
 // WorkingDay represents one day of work
 type WorkingDay struct {
 	gorm.Model
 
-	Start time.Time
-	End   time.Time
+	Date     time.Time `gorm:"index"`
+	Type     DayType   `gorm:"default:work"`
+	Sessions []Session
 
 	Brk  int `gorm:"column:break_in_m"`
 	Note string
 }
 
+// Session represents one continuous interval of work within a working day. A session belongs to the day it
+// starts on, even when it ends on the following day like a night shift.
+type Session struct {
+	gorm.Model
+
+	WorkingDayID uint `gorm:"index"`
+
+	Start time.Time
+	// End is nil as long as the session is running
+	End *time.Time
+
+	// Project is the name of the project the session was worked for
+	Project string `gorm:"index"`
+	// Tags contains free-form tags separated by comma
+	Tags string
+
+	// TimeZone is the IANA name or the offset of the zone the session was recorded in.
+	// It is left out of archives when empty, so archives of earlier versions keep their checksum.
+	TimeZone string `json:",omitempty"`
+}
+
+// Running reports whether the session has not been ended yet
+func (s *Session) Running() bool {
+	return s.End == nil
+}
+
+// Duration returns the length of the session. A running session counts up to now.
+func (s *Session) Duration() time.Duration {
+	if s.Running() {
+		return time.Since(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Overnight reports whether the session ends on a later day than it starts
+func (s *Session) Overnight() bool {
+	if s.Running() {
+		return false
+	}
+	sy, sm, sd := s.Start.Date()
+	ey, em, ed := s.End.In(s.Start.Location()).Date()
+	return time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC).After(time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *Session) String() string {
+	b := strings.Builder{}
+	if s.Running() {
+		b.WriteString(fmt.Sprintf("%s-running", s.Start.Format("15:04")))
+	} else {
+		b.WriteString(fmt.Sprintf("%s-%s", s.Start.Format("15:04"), s.End.Format("15:04")))
+	}
+	if s.Overnight() {
+		b.WriteString("+1")
+	}
+
+	if s.Project != "" {
+		b.WriteString(" @" + s.Project)
+	}
+	for _, tag := range s.TagList() {
+		b.WriteString(" #" + tag)
+	}
+	return b.String()
+}
+
+// Kind returns the type of the day. Days without type are working days.
+func (wd *WorkingDay) Kind() DayType {
+	if wd.Type == "" {
+		return Work
+	}
+	return wd.Type
+}
+
+// Start returns the start of the first session of the day
+func (wd *WorkingDay) Start() time.Time {
+	if len(wd.Sessions) == 0 {
+		return wd.Date
+	}
+	return wd.Sessions[0].Start
+}
+
+// End returns the end of the last session of the day. It is now when the last session is still running.
+func (wd *WorkingDay) End() time.Time {
+	if len(wd.Sessions) == 0 {
+		return wd.Date
+	}
+	last := wd.Sessions[len(wd.Sessions)-1]
+	if last.Running() {
+		return time.Now()
+	}
+	return *last.End
+}
+
+// Running returns the running session of the day or nil when there is none
+func (wd *WorkingDay) Running() *Session {
+	for i := range wd.Sessions {
+		if wd.Sessions[i].Running() {
+			return &wd.Sessions[i]
+		}
+	}
+	return nil
+}
+
+// Worked sums up all sessions of the day minus the break
+func (wd *WorkingDay) Worked() time.Duration {
+	var worked time.Duration
+	for _, s := range wd.Sessions {
+		worked += s.Duration()
+	}
+	return worked - time.Duration(wd.Brk)*time.Minute
+}
+
 func (wd *WorkingDay) String() string {
-	return fmt.Sprintf("%d: Worked from %s to %s taking %d min break (note: %s)", wd.ID, wd.Start, wd.End, wd.Brk, wd.Note)
+	if wd.Kind() != Work && len(wd.Sessions) == 0 {
+		return fmt.Sprintf("%d: %s on %s (note: %s)", wd.ID, wd.Kind(), wd.Date.Format("2006-01-02"), wd.Note)
+	}
+	return fmt.Sprintf("%d: Worked on %s in %s taking %d min break (note: %s)",
+		wd.ID, wd.Date.Format("2006-01-02"), wd.sessionsString(), wd.Brk, wd.Note)
 }
 
-func (wd *WorkingDay) ToRow() table.Row {
+// ToRow converts the working day into a table row. The target is the time which should be worked on that day.
+func (wd *WorkingDay) ToRow(target time.Duration, dateLayout string) table.Row {
 	return table.Row{
-		wd.Start, wd.End, wd.Brk, wd.Note,
+		wd.Date.Format(dateLayout), wd.Kind(), wd.sessionsString(), wd.Brk,
+		fmt.Sprintf("%.2f", wd.Worked().Hours()), fmt.Sprintf("%.2f", target.Hours()), wd.Note,
+	}
+}
+
+func (wd *WorkingDay) sessionsString() string {
+	sessions := make([]string, 0, len(wd.Sessions))
+	for _, s := range wd.Sessions {
+		sessions = append(sessions, s.String())
 	}
+	return strings.Join(sessions, ", ")
 }
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Restored means that a deleted working day was taken out of the trash
+	Restored ChangeAction = "restore"
+	// Purged means that a deleted working day was removed permanently
+	Purged ChangeAction = "purge"
+)
+
+// Trash loads all deleted working days with their sessions. The latest deleted day comes first.
+func (r *SqlRepo) Trash() ([]WorkingDay, error) {
+	var workingDays []WorkingDay
+
+	tx := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&workingDays)
+	if tx.Error != nil {
+		return nil, wrap(tx.Error, "load trash")
+	}
+
+	for i := range workingDays {
+		if err := loadTrashedSessions(r.db, &workingDays[i]); err != nil {
+			return nil, wrap(err, "load trash")
+		}
+	}
+	return workingDays, nil
+}
+
+// RestoreDeleted takes the deleted working day with the id out of the trash.
+// It fails with ErrConflict when another working day exists on the same date.
+func (r *SqlRepo) RestoreDeleted(id uint) (*WorkingDay, error) {
+	wd := &WorkingDay{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(wd, id).Error; err != nil {
+			return err
+		}
+		if err := loadTrashedSessions(tx, wd); err != nil {
+			return err
+		}
+
+		var count int64
+		s, e := startEnd(&wd.Date)
+		if err := tx.Model(&WorkingDay{}).Where("date BETWEEN ? and ?", s, e).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrConflict
+		}
+
+		if err := applyState(tx, nil, wd); err != nil {
+			return err
+		}
+		wd.DeletedAt = gorm.DeletedAt{}
+		for i := range wd.Sessions {
+			wd.Sessions[i].DeletedAt = gorm.DeletedAt{}
+		}
+		return record(tx, Restored, nil, wd)
+	})
+
+	if err != nil {
+		return nil, wrap(err, "restore deleted working day %d", id)
+	}
+	return wd, nil
+}
+
+// Purge permanently removes the working days which were deleted before the passed time. It returns the number of removed days.
+func (r *SqlRepo) Purge(before time.Time) (int, error) {
+	var workingDays []WorkingDay
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		before = before.UTC()
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&workingDays).Error; err != nil {
+			return err
+		}
+
+		for i := range workingDays {
+			wd := &workingDays[i]
+			if err := loadTrashedSessions(tx, wd); err != nil {
+				return err
+			}
+			if err := audit(tx, Purged, wd, nil); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("working_day_id = ?", wd.ID).Delete(&Session{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&WorkingDay{}, wd.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Sessions which were removed from a day are trash as well
+		return tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Session{}).Error
+	})
+
+	if err != nil {
+		return 0, wrap(err, "purge trash")
+	}
+	return len(workingDays), nil
+}
+
+// loadTrashedSessions loads the sessions which were deleted together with the deleted working day.
+// Sessions which were removed from the day before are left out.
+func loadTrashedSessions(tx *gorm.DB, wd *WorkingDay) error {
+	var sessions []Session
+	if err := tx.Unscoped().Where("working_day_id = ?", wd.ID).Order("start").Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	wd.Sessions = make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		if s.DeletedAt.Valid && !s.DeletedAt.Time.Before(wd.DeletedAt.Time) {
+			wd.Sessions = append(wd.Sessions, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSqlRepo_Trash(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 10, 8, 8, 0, 00, 000, time.Now().Location())
+	afternoon := start.Add(5 * time.Hour)
+	wd := newWorkingDay(start, start.Add(4*time.Hour), 30, "trashed")
+	wd.Sessions = append(wd.Sessions, Session{Start: afternoon, End: &afternoon})
+	if err := repo.Insert(wd); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session which was removed before the day was deleted stays removed
+	loaded, _ := repo.LoadDay(&start)
+	loaded.Sessions = loaded.Sessions[:1]
+	if err := repo.UpdateDay(*loaded); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := repo.Delete(*loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, err := repo.Trash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Note != "trashed" || len(trash[0].Sessions) != 1 || !trash[0].DeletedAt.Valid {
+		t.Fatalf("Unexpected trash %+v", trash)
+	}
+
+	// Restore is refused while another day exists on the date
+	if err = repo.Insert(newWorkingDay(start, start.Add(time.Hour), 0, "new")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.RestoreDeleted(trash[0].ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected conflict but got %v", err)
+	}
+	newDay, _ := repo.LoadDay(&start)
+	if err = repo.Delete(*newDay); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := repo.RestoreDeleted(trash[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd2, err := repo.LoadDay(&start)
+	if err != nil || wd2.ID != restored.ID || wd2.Note != "trashed" || len(wd2.Sessions) != 1 || wd2.Worked() != 3*time.Hour+30*time.Minute {
+		t.Fatalf("Unexpected restored day %v %v", wd2, err)
+	}
+	if _, err = repo.RestoreDeleted(trash[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Restored a live day: %v", err)
+	}
+
+	// Only days deleted before the time are purged
+	if purged, err := repo.Purge(time.Now().AddDate(0, 0, -1)); err != nil || purged != 0 {
+		t.Fatalf("Purged recent days: %d %v", purged, err)
+	}
+	if purged, err := repo.Purge(time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Fatalf("Expected 1 purged day but got %d %v", purged, err)
+	}
+	if trash, _ = repo.Trash(); len(trash) != 0 {
+		t.Fatalf("Trash is not empty after purge: %+v", trash)
+	}
+
+	entries, _ := repo.Audit(&start)
+	if last := entries[len(entries)-1]; last.Action != Purged || last.OldValue == "" {
+		t.Fatalf("Purge was not audited: %+v", last)
+	}
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Invalid marks impossible data which must not be stored
+	Invalid Severity = "error"
+	// Suspicious marks data which is possible but breaks a configured rule
+	Suspicious Severity = "warning"
+
+	// MaxSession is the longest possible session
+	MaxSession = 24 * time.Hour
+)
+
+// Severity tells whether a problem makes a working day impossible or only suspicious
+type Severity string
+
+// Problem is a finding of the validation of a working day
+type Problem struct {
+	Date     time.Time
+	Severity Severity
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s %s: %s", p.Date.Format("2006-01-02"), p.Severity, p.Message)
+}
+
+// InvalidError lists the impossible data of a working day. It matches ErrInvalid.
+type InvalidError struct {
+	Date     time.Time
+	Problems []Problem
+}
+
+func (e *InvalidError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		messages = append(messages, p.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", ErrInvalid, e.Date.Format("2006-01-02"), strings.Join(messages, "; "))
+}
+
+func (e *InvalidError) Unwrap() error {
+	return ErrInvalid
+}
+
+// BreakRule demands a minimum break once more than After was worked
+type BreakRule struct {
+	After   time.Duration
+	Minimum time.Duration
+}
+
+// Rules configure which working days are suspicious
+type Rules struct {
+	// MaxWorked is the time which should not be exceeded on a day. Zero disables the rule.
+	MaxWorked time.Duration
+	// Breaks are the required breaks. Gaps between sessions count as break.
+	Breaks []BreakRule
+}
+
+// Validate checks the working day for impossible data and for violations of the rules. Impossible data comes first.
+// Days with a running session are not checked against the rules.
+func (wd *WorkingDay) Validate(rules Rules) []Problem {
+	var invalid, suspicious []string
+
+	sessions := append([]Session(nil), wd.Sessions...)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+
+	if wd.Brk < 0 {
+		invalid = append(invalid, "break must not be negative")
+	}
+
+	var total, gaps time.Duration
+	running := 0
+	for i := range sessions {
+		s := &sessions[i]
+		if s.Running() {
+			running++
+		} else if s.End.Before(s.Start) {
+			invalid = append(invalid, fmt.Sprintf("session %s ends before it starts", s))
+		} else if s.Duration() > MaxSession {
+			invalid = append(invalid, fmt.Sprintf("session %s lasts longer than %s", s, formatHours(MaxSession)))
+		}
+		total += s.Duration()
+
+		if i == 0 {
+			continue
+		}
+		previous := &sessions[i-1]
+		if previous.Running() || s.Start.Before(*previous.End) {
+			invalid = append(invalid, fmt.Sprintf("sessions %s and %s overlap", previous, s))
+		} else {
+			gaps += s.Start.Sub(*previous.End)
+		}
+	}
+	if running > 1 {
+		invalid = append(invalid, "more than one session is running")
+	}
+
+	brk := time.Duration(wd.Brk) * time.Minute
+	// Impossible sessions make the time worked meaningless
+	if len(invalid) == 0 && running == 0 && brk > 0 && brk > total {
+		invalid = append(invalid, fmt.Sprintf("break of %s is longer than the %s worked", formatHours(brk), formatHours(total)))
+	}
+
+	// The rules apply once the day is finished
+	if running > 0 {
+		rules = Rules{}
+	}
+	worked := wd.Worked()
+	if rules.MaxWorked > 0 && worked > rules.MaxWorked {
+		suspicious = append(suspicious, fmt.Sprintf("worked %s which is more than %s", formatHours(worked), formatHours(rules.MaxWorked)))
+	}
+
+	// The rule with the longest work time applies
+	var required *BreakRule
+	for i := range rules.Breaks {
+		r := &rules.Breaks[i]
+		if worked > r.After && (required == nil || r.After > required.After) {
+			required = r
+		}
+	}
+	if required != nil && brk+gaps < required.Minimum {
+		suspicious = append(suspicious, fmt.Sprintf("break of %s is shorter than the %s required after %s of work",
+			formatHours(brk+gaps), formatHours(required.Minimum), formatHours(required.After)))
+	}
+
+	problems := make([]Problem, 0, len(invalid)+len(suspicious))
+	for _, m := range invalid {
+		problems = append(problems, Problem{Date: wd.Date, Severity: Invalid, Message: m})
+	}
+	for _, m := range suspicious {
+		problems = append(problems, Problem{Date: wd.Date, Severity: Suspicious, Message: m})
+	}
+	return problems
+}
+
+// Check validates the working day before it is stored. Impossible data is returned as *InvalidError, violated rules
+// are returned as warnings.
+func (wd *WorkingDay) Check(rules Rules) (warnings []string, err error) {
+	var invalid []Problem
+	for _, p := range wd.Validate(rules) {
+		if p.Severity == Invalid {
+			invalid = append(invalid, p)
+		} else {
+			warnings = append(warnings, p.Message)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return warnings, &InvalidError{Date: wd.Date, Problems: invalid}
+	}
+	return warnings, nil
+}
+
+// formatHours formats a duration like 9h30m or 45m
+func formatHours(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
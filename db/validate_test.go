@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkingDay_Validate(t *testing.T) {
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+	at := func(hour, min int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	}
+	session := func(start, end time.Time) Session {
+		return Session{Start: start, End: &end}
+	}
+	rules := Rules{MaxWorked: 10 * time.Hour, Breaks: []BreakRule{{6 * time.Hour, 30 * time.Minute}, {9 * time.Hour, 45 * time.Minute}}}
+
+	tests := []struct {
+		name     string
+		wd       WorkingDay
+		problems []string
+	}{
+		{"regular day", WorkingDay{Brk: 30, Sessions: []Session{session(at(8, 0), at(16, 30))}}, nil},
+		{"day off", WorkingDay{Type: Vacation}, nil},
+		{"night shift", WorkingDay{Brk: 30, Sessions: []Session{session(at(22, 0), at(30, 0))}}, nil},
+		{"gap is a break", WorkingDay{Sessions: []Session{session(at(8, 0), at(12, 0)), session(at(12, 30), at(16, 30))}}, nil},
+		{"running", WorkingDay{Brk: 30, Sessions: []Session{{Start: at(8, 0)}}}, nil},
+		{"end before start", WorkingDay{Sessions: []Session{session(at(16, 0), at(8, 0))}}, []string{"error: session 16:00-08:00 ends before it starts"}},
+		{"negative break", WorkingDay{Brk: -5, Sessions: []Session{session(at(8, 0), at(12, 0))}}, []string{"error: break must not be negative"}},
+		{"break longer than shift", WorkingDay{Brk: 90, Sessions: []Session{session(at(8, 0), at(9, 0))}}, []string{"error: break of 1h30m is longer than the 1h worked"}},
+		{"too long session", WorkingDay{Brk: 60, Sessions: []Session{session(at(6, 0), at(31, 0))}}, []string{
+			"error: session 06:00-07:00+1 lasts longer than 24h",
+			"warning: worked 24h which is more than 10h",
+		}},
+		{"overlap", WorkingDay{Sessions: []Session{session(at(8, 0), at(12, 0)), session(at(11, 0), at(13, 0))}}, []string{
+			"error: sessions 08:00-12:00 and 11:00-13:00 overlap",
+		}},
+		{"two running", WorkingDay{Sessions: []Session{{Start: at(8, 0)}, {Start: at(9, 0)}}}, []string{
+			"error: sessions 08:00-running and 09:00-running overlap",
+			"error: more than one session is running",
+		}},
+		{"long day", WorkingDay{Brk: 60, Sessions: []Session{session(at(6, 0), at(20, 0))}}, []string{"warning: worked 13h which is more than 10h"}},
+		{"missing break", WorkingDay{Sessions: []Session{session(at(8, 0), at(14, 30))}}, []string{
+			"warning: break of 0m is shorter than the 30m required after 6h of work",
+		}},
+		{"short break", WorkingDay{Brk: 30, Sessions: []Session{session(at(8, 0), at(18, 0))}}, []string{
+			"warning: break of 30m is shorter than the 45m required after 9h of work",
+		}},
+	}
+
+	for _, tt := range tests {
+		tt.wd.Date = day
+		var got []string
+		for _, p := range tt.wd.Validate(rules) {
+			got = append(got, string(p.Severity)+": "+p.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.problems, "\n") {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.problems, got)
+		}
+	}
+
+	// Without rules only impossible data is reported
+	long := WorkingDay{Sessions: []Session{session(at(6, 0), at(20, 0))}}
+	if problems := long.Validate(Rules{}); len(problems) != 0 {
+		t.Errorf("Expected no problems without rules but got %v", problems)
+	}
+}
+
+func TestWorkingDay_Check(t *testing.T) {
+	day := time.Date(2020, 8, 13, 0, 0, 0, 0, time.Now().Location())
+	end := day.Add(20 * time.Hour)
+	rules := Rules{MaxWorked: 10 * time.Hour}
+
+	long := WorkingDay{Date: day, Sessions: []Session{{Start: day.Add(6 * time.Hour), End: &end}}}
+	warnings, err := long.Check(rules)
+	if err != nil || len(warnings) != 1 || warnings[0] != "worked 14h which is more than 10h" {
+		t.Fatalf("Expected a warning but got %q (%v)", warnings, err)
+	}
+
+	long.Brk = -5
+	_, err = long.Check(rules)
+	var invalid *InvalidError
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &invalid) || len(invalid.Problems) != 1 {
+		t.Fatalf("Expected the negative break to be invalid but got %v", err)
+	}
+	if err.Error() != "invalid working day 2020-08-13: break must not be negative" {
+		t.Errorf("Unexpected message: %s", err)
+	}
+}
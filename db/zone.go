@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dates like the date of a working day are stored as midnight in UTC of their calendar day. Instants like the
+// start of a session are stored in UTC along with the name of the zone they were recorded in.
+
+// zones caches the loaded locations by name
+var zones sync.Map
+
+// utcColumns contains all columns with dates or instants. The audit table is left out as it is append-only.
+var utcColumns = []struct {
+	table  string
+	column string
+	date   bool
+}{
+	{"working_days", "date", true}, {"targets", "valid_from", true}, {"changes", "date", true},
+	{"sessions", "start", false}, {"sessions", "end", false},
+	{"working_days", "created_at", false}, {"working_days", "updated_at", false}, {"working_days", "deleted_at", false},
+	{"sessions", "created_at", false}, {"sessions", "updated_at", false}, {"sessions", "deleted_at", false},
+	{"targets", "created_at", false}, {"targets", "updated_at", false}, {"targets", "deleted_at", false},
+	{"projects", "created_at", false}, {"projects", "updated_at", false}, {"projects", "deleted_at", false},
+	{"changes", "created_at", false}, {"changes", "updated_at", false}, {"changes", "deleted_at", false},
+}
+
+// BeforeSave stores the date as calendar day
+func (wd *WorkingDay) BeforeSave(tx *gorm.DB) error {
+	wd.Date = dayOf(wd.Date)
+	return nil
+}
+
+// BeforeSave stores start and end in UTC. The zone is kept as long as it matches the offset of the start.
+func (s *Session) BeforeSave(tx *gorm.DB) error {
+	if s.TimeZone == "" || !sameOffset(s.Start, zoneOf(s.TimeZone)) {
+		s.TimeZone = zoneName(s.Start)
+	}
+
+	s.Start = s.Start.UTC()
+	if s.End != nil {
+		end := s.End.UTC()
+		s.End = &end
+	}
+	return nil
+}
+
+// AfterFind moves start and end into the zone the session was recorded in
+func (s *Session) AfterFind(tx *gorm.DB) error {
+	loc := zoneOf(s.TimeZone)
+
+	s.Start = s.Start.In(loc)
+	if s.End != nil {
+		end := s.End.In(loc)
+		s.End = &end
+	}
+	return nil
+}
+
+// BeforeSave stores the date from which the target is valid as calendar day
+func (t *Target) BeforeSave(tx *gorm.DB) error {
+	t.ValidFrom = dayOf(t.ValidFrom)
+	return nil
+}
+
+// BeforeSave stores the date of the change as calendar day
+func (c *Change) BeforeSave(tx *gorm.DB) error {
+	c.Date = dayOf(c.Date)
+	return nil
+}
+
+// BeforeCreate stores the date of the entry as calendar day
+func (a *AuditEntry) BeforeCreate(tx *gorm.DB) error {
+	a.Date = dayOf(a.Date)
+	return nil
+}
+
+// AfterFind moves the date into UTC
+func (wd *WorkingDay) AfterFind(tx *gorm.DB) error {
+	wd.Date = storedDay(wd.Date)
+	return nil
+}
+
+// AfterFind moves the date from which the target is valid into UTC
+func (t *Target) AfterFind(tx *gorm.DB) error {
+	t.ValidFrom = storedDay(t.ValidFrom)
+	return nil
+}
+
+// AfterFind moves the date of the change into UTC
+func (c *Change) AfterFind(tx *gorm.DB) error {
+	c.Date = storedDay(c.Date)
+	return nil
+}
+
+// AfterFind moves the date of the entry into UTC
+func (a *AuditEntry) AfterFind(tx *gorm.DB) error {
+	a.Date = storedDay(a.Date)
+	return nil
+}
+
+// storedDay returns the calendar day of a stored date. Drivers may read the UTC midnight in another location, while
+// old audit entries contain the local midnight.
+func storedDay(t time.Time) time.Time {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return dayOf(t)
+	}
+	return dayOf(t.UTC())
+}
+
+// dayOf returns the calendar day of t in its own location as midnight in UTC
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// startEnd returns the first and the last second of the calendar day of d in UTC
+func startEnd(d *time.Time) (time.Time, time.Time) {
+	s := dayOf(*d)
+	return s, s.Add(24*time.Hour - time.Second)
+}
+
+// zoneName returns the IANA name of the location of t. Locations without such a name are described by their offset.
+func zoneName(t time.Time) string {
+	name := t.Location().String()
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil && sameOffset(t, loc) {
+			return name
+		}
+	}
+
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// zoneOf loads a location by its IANA name or an offset like "+02:00". Unknown zones result in UTC.
+func zoneOf(name string) *time.Location {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc := time.UTC
+	if offset, err := time.Parse("-07:00", name); err == nil {
+		_, seconds := offset.Zone()
+		loc = time.FixedZone(name, seconds)
+	} else if l, err := time.LoadLocation(name); err == nil && name != "" {
+		loc = l
+	}
+
+	zones.Store(name, loc)
+	return loc
+}
+
+// sameOffset reports whether t has the same offset in loc
+func sameOffset(t time.Time, loc *time.Location) bool {
+	_, offset := t.Zone()
+	_, other := t.In(loc).Zone()
+	return offset == other
+}
+
+// migrateUTC moves all dates and instants, which were stored in a local zone, to UTC. Sessions without zone get
+// the one of the system.
+func migrateUTC(db *gorm.DB) error {
+	type stored struct {
+		ID    uint
+		Value time.Time
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE sessions SET time_zone = ? WHERE time_zone IS NULL OR time_zone = ''", zoneName(time.Now())).Error
+		if err != nil {
+			return err
+		}
+
+		for _, c := range utcColumns {
+			var rows []stored
+			query := fmt.Sprintf("SELECT id, `%s` AS value FROM %s WHERE `%s` IS NOT NULL AND `%s` NOT LIKE '%%+00:00'",
+				c.column, c.table, c.column, c.column)
+			if err := tx.Raw(query).Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			update := fmt.Sprintf("UPDATE %s SET `%s` = ? WHERE id = ?", c.table, c.column)
+			for _, row := range rows {
+				value := row.Value.UTC()
+				if c.date {
+					value = dayOf(row.Value)
+				}
+				if err := tx.Exec(update, value, row.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSqlRepo_StoresUTC(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	berlin := inZone(t, "Europe/Berlin")
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, berlin)
+	if err := repo.Insert(newWorkingDay(start, start.Add(8*time.Hour), 30, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored struct {
+		Date     string
+		Start    string
+		TimeZone string
+	}
+	err := repo.db.Raw("SELECT d.date || '' AS date, s.start || '' AS start, s.time_zone FROM working_days d " +
+		"JOIN sessions s ON s.working_day_id = d.id").Scan(&stored).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Date, "2020-08-13 00:00:00") || !strings.HasPrefix(stored.Start, "2020-08-13 06:00:00") ||
+		!strings.HasSuffix(stored.Start, "+00:00") || stored.TimeZone != "Europe/Berlin" {
+		t.Fatalf("Expected UTC with the zone of the entry but got %+v", stored)
+	}
+
+	// Sessions are loaded in the zone they were recorded in
+	wd, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.Start().Location().String() != "Europe/Berlin" || wd.Start().Hour() != 8 || !wd.Start().Equal(start) {
+		t.Fatalf("Expected start in Europe/Berlin but got %s", wd.Start())
+	}
+}
+
+func TestSqlRepo_DayBoundariesAcrossZones(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	tokyo := inZone(t, "Asia/Tokyo")
+	repo := newTestRepo(t)
+
+	// Recorded early in the morning in Tokyo while it is still the day before in New York
+	start := time.Date(2024, 3, 28, 6, 0, 0, 0, tokyo)
+	if err := repo.Insert(newWorkingDay(start, start.Add(9*time.Hour), 0, "tokyo")); err != nil {
+		t.Fatal(err)
+	}
+
+	newYork := inZone(t, "America/New_York")
+	day := time.Date(2024, 3, 28, 0, 0, 0, 0, newYork)
+	wd, err := repo.LoadDay(&day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.Note != "tokyo" || wd.Start().Hour() != 6 || wd.Worked() != 9*time.Hour {
+		t.Fatalf("Unexpected working day after traveling: %s", wd)
+	}
+
+	before := day.AddDate(0, 0, -1)
+	if _, err = repo.LoadDay(&before); err == nil {
+		t.Fatal("Working day moved to the day before")
+	}
+	days, err := repo.ListRange(&day, &day)
+	if err != nil || len(days) != 1 {
+		t.Fatalf("Expected one working day in the range but got %v (%v)", days, err)
+	}
+	end := time.Date(2024, 3, 28, 23, 0, 0, 0, newYork)
+	if err = repo.SetTarget(Target{ValidFrom: end, Thursday: 8 * 60}); err != nil {
+		t.Fatal(err)
+	}
+	if overtime := overtimeOf(t, repo); overtime != 60 {
+		t.Fatalf("Expected '%d' but got '%d'", 60, overtime)
+	}
+}
+
+func TestSqlRepo_DaylightSavingTime(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	berlin := inZone(t, "Europe/Berlin")
+	repo := newTestRepo(t)
+	repo.DefaultTarget = 0
+
+	// The clocks are put forward at 2:00 in spring and back at 3:00 in autumn
+	for _, day := range []struct {
+		date   time.Time
+		worked time.Duration
+	}{
+		{time.Date(2024, 3, 31, 0, 0, 0, 0, berlin), 5 * time.Hour},
+		{time.Date(2024, 10, 27, 0, 0, 0, 0, berlin), 7 * time.Hour},
+	} {
+		end := time.Date(day.date.Year(), day.date.Month(), day.date.Day(), 6, 0, 0, 0, berlin)
+		if err := repo.Insert(newWorkingDay(day.date, end, 0, "")); err != nil {
+			t.Fatal(err)
+		}
+
+		wd, err := repo.LoadDay(&day.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wd.Worked() != day.worked || wd.Start().Hour() != 0 || wd.End().Hour() != 6 {
+			t.Fatalf("Expected %s on %s but got %s", day.worked, day.date.Format("2006-01-02"), wd)
+		}
+	}
+
+	if overtime := overtimeOf(t, repo); overtime != 12*60 {
+		t.Fatalf("Expected '%d' but got '%d'", 12*60, overtime)
+	}
+}
+
+func TestNewRepo_MigratesToUTC(t *testing.T) {
+
+	defer os.Remove(dbName)
+
+	inZone(t, "Europe/Berlin")
+	repo := newTestRepo(t)
+
+	start := time.Date(2020, 8, 13, 8, 0, 0, 0, time.Local)
+	if err := repo.Insert(newWorkingDay(start, start.Add(8*time.Hour), 0, "local")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Databases of earlier versions contain the local times without zone and have no schema version
+	defer os.Remove(dbName + ".v0.bak")
+	stmts := []string{
+		"UPDATE working_days SET date = '2020-08-13 00:00:00+02:00'",
+		"UPDATE sessions SET start = '2020-08-13 08:00:00+02:00', `end` = '2020-08-13 16:00:00+02:00', time_zone = ''",
+		"DROP TABLE schema_version",
+	}
+	for _, stmt := range stmts {
+		if err := repo.db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo = newTestRepo(t)
+	var date, end string
+	if err := repo.db.Raw("SELECT date || '' FROM working_days").Row().Scan(&date); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.db.Raw("SELECT `end` || '' FROM sessions").Row().Scan(&end); err != nil {
+		t.Fatal(err)
+	}
+	if date != "2020-08-13 00:00:00+00:00" || end != "2020-08-13 14:00:00+00:00" {
+		t.Fatalf("Expected the date and end in UTC but got %s and %s", date, end)
+	}
+
+	wd, err := repo.LoadDay(&start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wd.Start().Equal(start) || wd.Sessions[0].TimeZone != "Europe/Berlin" || wd.Worked() != 8*time.Hour {
+		t.Fatalf("Working day was not migrated correctly: %s", wd)
+	}
+}
+
+func TestZoneOf(t *testing.T) {
+	instant := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name   string
+		offset int
+	}{
+		{"Europe/Berlin", 2 * 3600},
+		{"America/New_York", -4 * 3600},
+		{"+05:30", 5*3600 + 30*60},
+		{"-03:00", -3 * 3600},
+		{"UTC", 0},
+		{"Mars/Olympus", 0},
+	} {
+		if _, offset := instant.In(zoneOf(tt.name)).Zone(); offset != tt.offset {
+			t.Errorf("Expected offset %d for %s but got %d", tt.offset, tt.name, offset)
+		}
+	}
+
+	// Zones without IANA name are stored by their offset
+	if name := zoneName(instant.In(time.FixedZone("", 5*3600+30*60))); name != "+05:30" {
+		t.Errorf("Expected +05:30 but got %s", name)
+	}
+}
+
+// inZone makes the zone the local one until the end of the test
+func inZone(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("Time zone %s is not available: %v", name, err)
+	}
+
+	old := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = old })
+	return loc
+}
@@ -3,13 +3,15 @@ module github.com/corka149/timed
 go 1.15
 
 require (
-	github.com/crazy-max/xgo v0.11.0 // indirect
+	github.com/gdamore/tcell/v2 v2.2.0
 	github.com/jedib0t/go-pretty/v6 v6.2.7
 	github.com/mattn/go-sqlite3 v1.14.4 // indirect
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/jwalterweatherman v1.1.0
 	github.com/spf13/pflag v1.0.5 // indirect
+	gopkg.in/yaml.v2 v2.4.0
+	gorm.io/driver/postgres v1.2.3
 	gorm.io/driver/sqlite v1.1.3
-	gorm.io/gorm v1.20.5
+	gorm.io/gorm v1.22.3
 )
@@ -22,6 +22,9 @@ THE SOFTWARE.
 package main
 
 import (
+	// Time zones are available on systems without zone database as well
+	_ "time/tzdata"
+
 	"github.com/corka149/timed/cmd"
 )
 
@@ -0,0 +1,708 @@
+/*
+Package tui is the full-screen terminal UI of timed
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/corka149/timed/when"
+	"github.com/gdamore/tcell/v2"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+const (
+	// cellWidth is the width of a day in the calendar
+	cellWidth = 9
+	// detailX is the column of the day detail pane
+	detailX = 7*cellWidth + 3
+
+	help = "←→↑↓ day  PgUp/PgDn month  t today  Tab session  a add  s start  e end  b break  n note  i in  o out  q quit"
+)
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleTitle    = styleDefault.Bold(true)
+	styleDim      = styleDefault.Foreground(tcell.ColorGray)
+	styleMet      = styleDefault.Foreground(tcell.ColorGreen)
+	styleShort    = styleDefault.Foreground(tcell.ColorYellow)
+	styleOff      = styleDefault.Foreground(tcell.ColorBlue)
+	styleRunning  = styleDefault.Foreground(tcell.ColorTeal)
+	styleInvalid  = styleDefault.Foreground(tcell.ColorRed)
+	styleSelected = styleDefault.Reverse(true)
+
+	// kinds are the short names of days off in the calendar
+	kinds = map[db.DayType]string{db.Vacation: "vac", db.Sick: "sick", db.Holiday: "hol", db.CompTime: "comp"}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// App shows a month of working days with the details of the selected day. Changes are validated and stored in
+// the repo right away.
+type App struct {
+	repo  db.Repo
+	rules db.Rules
+
+	// selected is the midnight of the selected day
+	selected time.Time
+	// session is the index of the selected session of the selected day
+	session int
+
+	// days contains the working days of the selected month by date
+	days     map[string]*db.WorkingDay
+	schedule db.Schedule
+	running  *db.WorkingDay
+	overtime time.Duration
+	loadedAt time.Time
+
+	prompt  *prompt
+	message string
+	failed  bool
+
+	// now returns the current time. Tests replace it.
+	now func() time.Time
+}
+
+// New creates the UI on top of the repo. Working days which break the rules are stored with a warning, impossible
+// ones are refused.
+func New(repo db.Repo, rules db.Rules) *App {
+	a := &App{repo: repo, rules: rules, now: time.Now}
+	a.selected = midnight(a.now())
+	return a
+}
+
+// Run shows the UI on the initialized screen until the user quits. The screen is redrawn every second to keep the
+// timer of a running session going.
+func (a *App) Run(screen tcell.Screen) error {
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				_ = screen.PostEvent(tcell.NewEventInterrupt(nil))
+			}
+		}
+	}()
+
+	for {
+		a.draw(screen)
+		switch ev := screen.PollEvent().(type) {
+		case nil:
+			return nil
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if a.handle(ev) {
+				return nil
+			}
+		}
+	}
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// prompt reads a value in the last line of the screen
+type prompt struct {
+	label string
+	value string
+	apply func(value string) error
+}
+
+// handle processes a key. It reports whether the user wants to quit.
+func (a *App) handle(ev *tcell.EventKey) bool {
+	if a.prompt != nil {
+		a.handlePrompt(ev)
+		return false
+	}
+	a.message, a.failed = "", false
+
+	var err error
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		return true
+	case tcell.KeyLeft:
+		err = a.move(-1)
+	case tcell.KeyRight:
+		err = a.move(1)
+	case tcell.KeyUp:
+		err = a.move(-7)
+	case tcell.KeyDown:
+		err = a.move(7)
+	case tcell.KeyPgUp:
+		err = a.moveMonth(-1)
+	case tcell.KeyPgDn:
+		err = a.moveMonth(1)
+	case tcell.KeyTab:
+		if wd := a.day(); wd != nil && len(wd.Sessions) > 0 {
+			a.session = (a.session + 1) % len(wd.Sessions)
+		}
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case 'h':
+			err = a.move(-1)
+		case 'l':
+			err = a.move(1)
+		case 'k':
+			err = a.move(-7)
+		case 'j':
+			err = a.move(7)
+		case 't':
+			err = a.jump(midnight(a.now()))
+		case 'a':
+			a.editSession()
+		case 's':
+			a.editStart()
+		case 'e':
+			a.editEnd()
+		case 'b':
+			a.editBreak()
+		case 'n':
+			a.editNote()
+		case 'i':
+			err = a.clockIn()
+		case 'o':
+			err = a.clockOut()
+		case 'r':
+			err = a.load()
+		}
+	}
+
+	if err != nil {
+		a.message, a.failed = err.Error(), true
+	}
+	return false
+}
+
+// handlePrompt edits the value of the prompt. Enter applies it and Escape cancels it.
+func (a *App) handlePrompt(ev *tcell.EventKey) {
+	p := a.prompt
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		a.prompt = nil
+	case tcell.KeyEnter:
+		a.prompt = nil
+		if err := p.apply(strings.TrimSpace(p.value)); err != nil {
+			a.message, a.failed = err.Error(), true
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if r := []rune(p.value); len(r) > 0 {
+			p.value = string(r[:len(r)-1])
+		}
+	case tcell.KeyCtrlU:
+		p.value = ""
+	case tcell.KeyRune:
+		p.value += string(ev.Rune())
+	}
+}
+
+// move selects the day n days after the selected one
+func (a *App) move(n int) error {
+	return a.jump(a.selected.AddDate(0, 0, n))
+}
+
+// jump selects the day. The month is loaded when it changes.
+func (a *App) jump(day time.Time) error {
+	before := a.selected
+	a.selected = day
+	a.session = 0
+	if a.selected.Month() != before.Month() || a.selected.Year() != before.Year() {
+		return a.load()
+	}
+	return nil
+}
+
+// moveMonth selects the same day n months later or the last day of that month when it is shorter
+func (a *App) moveMonth(n int) error {
+	first := time.Date(a.selected.Year(), a.selected.Month()+time.Month(n), 1, 0, 0, 0, 0, a.selected.Location())
+	day := a.selected.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	a.selected = first.AddDate(0, 0, day-1)
+	a.session = 0
+	return a.load()
+}
+
+// load reads the working days of the selected month, the running session and the overtime
+func (a *App) load() error {
+	first := time.Date(a.selected.Year(), a.selected.Month(), 1, 0, 0, 0, 0, a.selected.Location())
+	last := first.AddDate(0, 1, -1)
+	days, err := a.repo.ListRange(&first, &last)
+	if err != nil {
+		return err
+	}
+	a.days = make(map[string]*db.WorkingDay, len(days))
+	for i := range days {
+		a.days[days[i].Date.Format("2006-01-02")] = &days[i]
+	}
+
+	if a.schedule, err = a.repo.Schedule(); err != nil {
+		return err
+	}
+	if a.running, err = a.repo.LoadRunning(); errors.Is(err, db.ErrNotFound) {
+		a.running = nil
+	} else if err != nil {
+		return err
+	}
+	overtime, err := a.repo.Overtime()
+	if err != nil {
+		return err
+	}
+	a.overtime, a.loadedAt = time.Duration(overtime)*time.Minute, a.now()
+
+	if wd := a.day(); wd == nil || a.session >= len(wd.Sessions) {
+		a.session = 0
+	}
+	return nil
+}
+
+// day returns the working day of the selected date. It is nil when there is none.
+func (a *App) day() *db.WorkingDay {
+	return a.days[a.selected.Format("2006-01-02")]
+}
+
+// ask opens a prompt for the value
+func (a *App) ask(label string, value string, apply func(value string) error) {
+	a.prompt = &prompt{label: label, value: value, apply: apply}
+}
+
+// editSession adds a session like 08:00-16:30 to the selected day. Without end the session is running.
+func (a *App) editSession() {
+	a.ask("Session (start-end)", "", func(value string) error {
+		parts := strings.SplitN(value, "-", 2)
+		return a.store(a.selected, true, func(wd *db.WorkingDay) error {
+			start, err := when.Clock(strings.TrimSpace(parts[0]), a.selected, a.now())
+			if err != nil {
+				return err
+			}
+			s := db.Session{Start: start}
+			if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+				if s.End, err = a.end(parts[1], start); err != nil {
+					return err
+				}
+			} else if err = a.checkRunning(a.selected); err != nil {
+				return err
+			}
+			wd.Sessions = append(wd.Sessions, s)
+			return nil
+		})
+	})
+}
+
+// editStart changes the start of the selected session
+func (a *App) editStart() {
+	s := a.selectedSession()
+	if s == nil {
+		a.message, a.failed = "No session selected - add one with a", true
+		return
+	}
+	a.ask("Start", s.Start.Format("15:04"), func(value string) error {
+		return a.storeSession(func(s *db.Session) error {
+			start, err := when.Clock(value, a.selected, a.now())
+			s.Start = start
+			return err
+		})
+	})
+}
+
+// editEnd changes the end of the selected session. An empty end makes it running.
+func (a *App) editEnd() {
+	s := a.selectedSession()
+	if s == nil {
+		a.message, a.failed = "No session selected - add one with a", true
+		return
+	}
+	value := ""
+	if s.End != nil {
+		value = s.End.Format("15:04")
+	}
+	a.ask("End", value, func(value string) error {
+		return a.storeSession(func(s *db.Session) error {
+			if value == "" {
+				s.End = nil
+				return a.checkRunning(a.selected)
+			}
+			end, err := a.end(value, s.Start)
+			s.End = end
+			return err
+		})
+	})
+}
+
+// editBreak changes the break of the selected day in minutes
+func (a *App) editBreak() {
+	wd := a.day()
+	if wd == nil {
+		a.message, a.failed = "No working day - add a session with a", true
+		return
+	}
+	a.ask("Break in minutes", strconv.Itoa(wd.Brk), func(value string) error {
+		brk, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid break '%s'", value)
+		}
+		return a.store(a.selected, false, func(wd *db.WorkingDay) error {
+			wd.Brk = brk
+			return nil
+		})
+	})
+}
+
+// editNote changes the note of the selected day
+func (a *App) editNote() {
+	wd := a.day()
+	if wd == nil {
+		a.message, a.failed = "No working day - add a session with a", true
+		return
+	}
+	a.ask("Note", wd.Note, func(value string) error {
+		return a.store(a.selected, false, func(wd *db.WorkingDay) error {
+			wd.Note = value
+			return nil
+		})
+	})
+}
+
+// checkRunning refuses another running session on the date while a session of another day is running
+func (a *App) checkRunning(date time.Time) error {
+	if a.running != nil && a.running.Date.Format("2006-01-02") != date.Format("2006-01-02") {
+		return fmt.Errorf("%w: a session is already running since %s", db.ErrConflict, a.running.Running().Start.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// clockIn starts a running session today
+func (a *App) clockIn() error {
+	if a.running != nil {
+		return fmt.Errorf("a session is already running since %s", a.running.Running().Start.Format("2006-01-02 15:04"))
+	}
+	now := a.now()
+	return a.store(midnight(now), true, func(wd *db.WorkingDay) error {
+		wd.Sessions = append(wd.Sessions, db.Session{Start: now})
+		return nil
+	})
+}
+
+// clockOut ends the running session now
+func (a *App) clockOut() error {
+	if a.running == nil {
+		return errors.New("no running session found")
+	}
+	now := a.now()
+	return a.store(a.running.Date, false, func(wd *db.WorkingDay) error {
+		s := wd.Running()
+		if s == nil {
+			return errors.New("no running session found")
+		}
+		s.End = &now
+		return nil
+	})
+}
+
+// end parses the end of a session. An end before the start lies on the following day.
+func (a *App) end(value string, start time.Time) (*time.Time, error) {
+	end, err := when.Clock(strings.TrimSpace(value), start, a.now())
+	if err != nil {
+		return nil, err
+	}
+	end = when.EndAfter(start, end)
+	return &end, nil
+}
+
+// selectedSession returns the selected session of the selected day or nil when there is none
+func (a *App) selectedSession() *db.Session {
+	wd := a.day()
+	if wd == nil || a.session >= len(wd.Sessions) {
+		return nil
+	}
+	return &wd.Sessions[a.session]
+}
+
+// storeSession changes the selected session of the selected day
+func (a *App) storeSession(edit func(s *db.Session) error) error {
+	index := a.session
+	return a.store(a.selected, false, func(wd *db.WorkingDay) error {
+		if index >= len(wd.Sessions) {
+			return errors.New("the session does not exist anymore")
+		}
+		return edit(&wd.Sessions[index])
+	})
+}
+
+// store changes the working day of the date. A missing working day is created when create is set. The day is
+// validated before it is stored.
+func (a *App) store(date time.Time, create bool, edit func(wd *db.WorkingDay) error) error {
+	wd, err := a.repo.LoadDay(&date)
+	insert := errors.Is(err, db.ErrNotFound)
+	if insert && create {
+		wd = &db.WorkingDay{Date: date}
+	} else if insert {
+		return errors.New("no working day - add a session with a")
+	} else if err != nil {
+		return err
+	}
+
+	if err = edit(wd); err != nil {
+		return err
+	}
+
+	warnings, err := wd.Check(a.rules)
+	if err != nil {
+		return err
+	}
+
+	if insert {
+		err = a.repo.Insert(*wd)
+	} else {
+		err = a.repo.UpdateDay(*wd)
+	}
+	if err != nil {
+		return err
+	}
+	if err = a.load(); err != nil {
+		return err
+	}
+
+	if len(warnings) > 0 {
+		a.message, a.failed = "Warning: "+strings.Join(warnings, "; "), false
+	} else {
+		a.message, a.failed = "Saved "+wd.Date.Format("2006-01-02"), false
+	}
+	return nil
+}
+
+// draw renders the calendar, the selected day and the footer
+func (a *App) draw(screen tcell.Screen) {
+	screen.Clear()
+	width, height := screen.Size()
+	now := a.now()
+
+	drawText(screen, 1, 0, styleTitle, "timed  "+a.selected.Format("January 2006"))
+	a.drawCalendar(screen, 1, 2, now)
+	a.drawDetail(screen, detailX, 2)
+
+	// Footer with the live balance
+	overtime := a.overtime
+	footer := ""
+	if a.running != nil {
+		s := a.running.Running()
+		overtime += now.Sub(a.loadedAt)
+		footer = fmt.Sprintf("   Running since %s for %s", s.Start.Format("15:04"), timer(now.Sub(s.Start)))
+	}
+	drawText(screen, 1, height-3, styleTitle, "Overtime "+signed(overtime))
+	drawText(screen, 1+len("Overtime ")+len(signed(overtime)), height-3, styleRunning, footer)
+
+	switch {
+	case a.prompt != nil:
+		text := a.prompt.label + ": " + a.prompt.value
+		drawText(screen, 1, height-1, styleDefault, text)
+		screen.ShowCursor(1+len([]rune(text)), height-1)
+	case a.message != "":
+		style := styleDefault
+		if a.failed {
+			style = styleInvalid
+		}
+		drawText(screen, 1, height-1, style, truncate(a.message, width-2))
+		screen.HideCursor()
+	default:
+		drawText(screen, 1, height-1, styleDim, truncate(help, width-2))
+		screen.HideCursor()
+	}
+
+	screen.Show()
+}
+
+// drawCalendar renders the month of the selected day with the worked hours per day. Weeks start on monday.
+func (a *App) drawCalendar(screen tcell.Screen, x int, y int, now time.Time) {
+	for i, name := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		drawText(screen, x+i*cellWidth, y, styleTitle, name)
+	}
+
+	first := time.Date(a.selected.Year(), a.selected.Month(), 1, 0, 0, 0, 0, a.selected.Location())
+	offset := (int(first.Weekday()) + 6) % 7
+	today := midnight(now)
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		cell := offset + d.Day() - 1
+		cx, cy := x+(cell%7)*cellWidth, y+1+(cell/7)*2
+
+		style := styleDefault
+		if cell%7 >= 5 {
+			style = styleDim
+		}
+		text := fmt.Sprintf("%2d", d.Day())
+		if wd := a.days[d.Format("2006-01-02")]; wd != nil {
+			summary, s := a.summary(wd)
+			text += " " + summary
+			style = s
+		}
+		if d.Equal(today) {
+			style = style.Underline(true)
+		}
+		if d.Equal(a.selected) {
+			style = style.Reverse(true)
+		}
+		drawText(screen, cx, cy, style, fmt.Sprintf("%-*s", cellWidth-1, text))
+	}
+}
+
+// summary returns the short form of a working day in the calendar and its style
+func (a *App) summary(wd *db.WorkingDay) (string, tcell.Style) {
+	for _, p := range wd.Validate(db.Rules{}) {
+		if p.Severity == db.Invalid {
+			return "!" + clock(a.worked(wd)), styleInvalid
+		}
+	}
+	if wd.Kind() != db.Work && len(wd.Sessions) == 0 {
+		return kinds[wd.Kind()], styleOff
+	}
+	worked := a.worked(wd)
+	if wd.Running() != nil {
+		return clock(worked), styleRunning
+	}
+	if worked >= a.schedule.TargetOfDay(wd) {
+		return clock(worked), styleMet
+	}
+	return clock(worked), styleShort
+}
+
+// worked returns the time worked on the day. A running session counts up to now.
+func (a *App) worked(wd *db.WorkingDay) time.Duration {
+	worked := -time.Duration(wd.Brk) * time.Minute
+	for i := range wd.Sessions {
+		if s := &wd.Sessions[i]; s.Running() {
+			worked += a.now().Sub(s.Start)
+		} else {
+			worked += s.Duration()
+		}
+	}
+	return worked
+}
+
+// drawDetail renders the sessions, break, note and problems of the selected day
+func (a *App) drawDetail(screen tcell.Screen, x int, y int) {
+	drawText(screen, x, y, styleTitle, a.selected.Format("Monday, 2006-01-02"))
+
+	wd := a.day()
+	if wd == nil {
+		drawText(screen, x, y+2, styleDim, "No entries - press a to add a session")
+		return
+	}
+
+	line := y + 2
+	drawText(screen, x, line, styleDefault, "Type     "+string(wd.Kind()))
+	line++
+	drawText(screen, x, line, styleDefault, "Sessions")
+	for i := range wd.Sessions {
+		line++
+		style := styleDefault
+		if i == a.session {
+			style = styleSelected
+		}
+		drawText(screen, x+2, line, style, wd.Sessions[i].String())
+	}
+	line++
+	drawText(screen, x, line, styleDefault, fmt.Sprintf("Break    %dm", wd.Brk))
+	line++
+	drawText(screen, x, line, styleDefault,
+		fmt.Sprintf("Worked   %s of %s", clock(a.worked(wd)), clock(a.schedule.TargetOfDay(wd))))
+	line++
+	drawText(screen, x, line, styleDefault, "Note     "+wd.Note)
+
+	line++
+	for _, p := range wd.Validate(a.rules) {
+		line++
+		style := styleShort
+		if p.Severity == db.Invalid {
+			style = styleInvalid
+		}
+		drawText(screen, x, line, style, fmt.Sprintf("%s: %s", p.Severity, p.Message))
+	}
+}
+
+// drawText writes the text starting at the position
+func drawText(screen tcell.Screen, x int, y int, style tcell.Style, text string) {
+	for _, r := range text {
+		screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+// truncate shortens the text to the width
+func truncate(text string, width int) string {
+	if r := []rune(text); width >= 0 && len(r) > width {
+		return string(r[:width])
+	}
+	return text
+}
+
+// midnight returns the start of the day of t
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clock formats a duration like 7:30
+func clock(d time.Duration) string {
+	d = d.Round(time.Minute)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%d:%02d", sign, int(d.Hours()), int(d.Minutes())%60)
+}
+
+// timer formats the duration of a running session like 2:05:09
+func timer(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// signed formats a duration with its sign like +1:15
+func signed(d time.Duration) string {
+	if d.Round(time.Minute) >= 0 {
+		return "+" + clock(d)
+	}
+	return clock(d)
+}
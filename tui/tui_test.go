@@ -0,0 +1,248 @@
+package tui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corka149/timed/db"
+	"github.com/gdamore/tcell/v2"
+)
+
+// newTestApp shows a fresh database. The clock stands still on a thursday afternoon.
+func newTestApp(t *testing.T) (*App, *db.SqlRepo) {
+	dir, err := ioutil.TempDir("", "timed-tui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repo, err := db.NewRepo(filepath.Join(dir, "timed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.DefaultTarget = 8 * time.Hour
+
+	rules := db.Rules{MaxWorked: 10 * time.Hour, Breaks: []db.BreakRule{{After: 6 * time.Hour, Minimum: 30 * time.Minute}}}
+	a := New(repo, rules)
+	now := time.Date(2024, 3, 28, 17, 0, 0, 0, time.Local)
+	a.now = func() time.Time { return now }
+	a.selected = midnight(now)
+	if err = a.load(); err != nil {
+		t.Fatal(err)
+	}
+	return a, repo
+}
+
+// press sends the keys to the app. Strings are typed rune by rune.
+func press(a *App, keys ...interface{}) {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case tcell.Key:
+			a.handle(tcell.NewEventKey(k, 0, tcell.ModNone))
+		case string:
+			for _, r := range k {
+				a.handle(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+		}
+	}
+}
+
+// render draws the app and returns the text of the screen
+func render(t *testing.T, a *App) string {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(160, 30)
+
+	a.draw(screen)
+	cells, width, _ := screen.GetContents()
+	b := strings.Builder{}
+	for i, c := range cells {
+		if i > 0 && i%width == 0 {
+			b.WriteString("\n")
+		}
+		if len(c.Runes) > 0 {
+			b.WriteRune(c.Runes[0])
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+func loadDay(t *testing.T, repo db.Repo, year int, month time.Month, day int) *db.WorkingDay {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	wd, err := repo.LoadDay(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wd
+}
+
+func TestApp_Calendar(t *testing.T) {
+	a, repo := newTestApp(t)
+
+	start := time.Date(2024, 3, 27, 8, 0, 0, 0, time.Local)
+	end := start.Add(8*time.Hour + 30*time.Minute)
+	if err := repo.Insert(db.WorkingDay{Date: midnight(start), Sessions: []db.Session{{Start: start, End: &end, Project: ""}}, Brk: 30, Note: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Insert(db.WorkingDay{Date: time.Date(2024, 3, 25, 0, 0, 0, 0, time.Local), Type: db.Vacation}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	screen := render(t, a)
+	for _, want := range []string{"March 2024", "Mon", "25 vac", "27 8:00", "Thursday, 2024-03-28", "No entries", "Overtime +0:00"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Screen misses %q:\n%s", want, screen)
+		}
+	}
+
+	// The details follow the selection
+	press(a, tcell.KeyLeft)
+	screen = render(t, a)
+	for _, want := range []string{"Wednesday, 2024-03-27", "08:00-16:30", "Break    30m", "Worked   8:00 of 8:00", "Note     api"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Screen misses %q:\n%s", want, screen)
+		}
+	}
+
+	press(a, tcell.KeyPgDn)
+	if a.selected.Month() != time.April || a.selected.Day() != 27 || len(a.days) != 0 {
+		t.Fatalf("Expected april without days but got %s with %d days", a.selected, len(a.days))
+	}
+	press(a, "t")
+	if !a.selected.Equal(midnight(a.now())) || len(a.days) != 2 {
+		t.Fatalf("Expected today with two days but got %s with %d days", a.selected, len(a.days))
+	}
+}
+
+func TestApp_Edit(t *testing.T) {
+	a, repo := newTestApp(t)
+
+	press(a, tcell.KeyLeft, "a", "08:00-16:30", tcell.KeyEnter)
+	wd := loadDay(t, repo, 2024, 3, 27)
+	if len(wd.Sessions) != 1 || wd.Worked() != 8*time.Hour+30*time.Minute {
+		t.Fatalf("Session was not added: %s", wd)
+	}
+	if !strings.Contains(a.message, "Warning") || a.failed {
+		t.Fatalf("Expected a warning about the missing break but got %q", a.message)
+	}
+
+	press(a, "b", tcell.KeyCtrlU, "30", tcell.KeyEnter)
+	press(a, "s", tcell.KeyBackspace2, tcell.KeyBackspace2, "30", tcell.KeyEnter)
+	press(a, "e", tcell.KeyCtrlU, "17:00", tcell.KeyEnter)
+	press(a, "n", "Workshop", tcell.KeyEnter)
+	wd = loadDay(t, repo, 2024, 3, 27)
+	if wd.Brk != 30 || wd.Start().Minute() != 30 || wd.End().Hour() != 17 || wd.Note != "Workshop" {
+		t.Fatalf("Changes were not stored: %s", wd)
+	}
+
+	// An end before the start lies on the following day
+	press(a, "e", tcell.KeyCtrlU, "02:00", tcell.KeyEnter)
+	if wd = loadDay(t, repo, 2024, 3, 27); wd.End().Day() != 28 || wd.End().Hour() != 2 {
+		t.Fatalf("Night shift was not stored: %s", wd)
+	}
+
+	// Impossible days and cancelled prompts are not stored
+	press(a, "b", tcell.KeyCtrlU, "9999", tcell.KeyEnter)
+	if !a.failed || !strings.Contains(a.message, "break of") {
+		t.Fatalf("Expected an error but got %q", a.message)
+	}
+	press(a, "n", "ignored", tcell.KeyEscape)
+	if wd = loadDay(t, repo, 2024, 3, 27); wd.Brk != 30 || wd.Note != "Workshop" {
+		t.Fatalf("Rejected change was stored: %s", wd)
+	}
+
+	// Editing requires a working day
+	press(a, tcell.KeyRight, "s")
+	if a.prompt != nil || !a.failed {
+		t.Fatal("Started editing a day without session")
+	}
+}
+
+func TestApp_Clock(t *testing.T) {
+	a, repo := newTestApp(t)
+
+	// The overtime of the repo counts running sessions up to the real time
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	press(a, "t", "i")
+	if a.running == nil || a.failed {
+		t.Fatalf("Session was not started: %s", a.message)
+	}
+	press(a, "i")
+	if !a.failed {
+		t.Fatal("Started a second session")
+	}
+
+	// The timer and the balance keep running
+	a.now = func() time.Time { return now.Add(time.Hour + 5*time.Minute + 9*time.Second) }
+	screen := render(t, a)
+	if !strings.Contains(screen, "Running since "+now.Format("15:04")+" for 1:05:09") || !strings.Contains(screen, "Overtime -6:55") {
+		t.Fatalf("Unexpected footer:\n%s", screen)
+	}
+
+	press(a, "o")
+	wd := loadDay(t, repo, now.Year(), now.Month(), now.Day())
+	if a.running != nil || wd.Running() != nil || wd.Worked() != time.Hour+5*time.Minute+9*time.Second {
+		t.Fatalf("Session was not ended: %s", wd)
+	}
+	press(a, "o")
+	if !a.failed {
+		t.Fatal("Ended a session which is not running")
+	}
+}
+
+func TestApp_SingleRunningSession(t *testing.T) {
+	a, repo := newTestApp(t)
+
+	press(a, "a", "08:00", tcell.KeyEnter)
+	if a.running == nil || a.failed {
+		t.Fatalf("Running session was not added: %s", a.message)
+	}
+
+	// Another day must not get a running session too
+	press(a, tcell.KeyLeft, "a", "08:00", tcell.KeyEnter)
+	if !a.failed || !strings.Contains(a.message, "already running") {
+		t.Fatalf("Expected the second running session to be refused but got %q", a.message)
+	}
+	press(a, "a", "08:00-12:00", tcell.KeyEnter)
+	press(a, "e", tcell.KeyCtrlU, tcell.KeyEnter)
+	if !a.failed || !strings.Contains(a.message, "already running") {
+		t.Fatalf("Expected clearing the end to be refused but got %q", a.message)
+	}
+	if wd := loadDay(t, repo, 2024, 3, 27); wd.Running() != nil || len(wd.Sessions) != 1 {
+		t.Fatalf("Stored a second running session: %s", wd)
+	}
+
+	// The day of the running session is not affected
+	press(a, tcell.KeyRight, "e", tcell.KeyEnter)
+	if a.failed || a.running == nil {
+		t.Fatalf("Could not edit the running session: %q", a.message)
+	}
+}
+
+func TestApp_Run(t *testing.T) {
+	a, _ := newTestApp(t)
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	if err := a.Run(screen); err != nil {
+		t.Fatal(err)
+	}
+}
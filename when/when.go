@@ -0,0 +1,205 @@
+/*
+Package when parses the dates and times users enter on the command line
+
+Copyright © 2020 Sebastian Ziemann <corka149@mailbox.org>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package when
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===================
+// ===== GLOBALS =====
+// ===================
+
+var (
+	relativeDate = regexp.MustCompile(`^([+-]\d+)([dw])$`)
+	isoWeek      = regexp.MustCompile(`^(\d{4})-?w(\d{1,2})$`)
+
+	weekdays = map[string]time.Weekday{
+		"mon": time.Monday, "monday": time.Monday,
+		"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+		"wed": time.Wednesday, "wednesday": time.Wednesday,
+		"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+		"fri": time.Friday, "friday": time.Friday,
+		"sat": time.Saturday, "saturday": time.Saturday,
+		"sun": time.Sunday, "sunday": time.Sunday,
+	}
+)
+
+// ==================
+// ===== PUBLIC =====
+// ==================
+
+// Date parses a date relative to now and returns its midnight in the location of now. Supported are
+// "today", "yesterday", "tomorrow", weekdays like "mon" (the latest one including today), "last friday",
+// "next monday", offsets like "-2d" or "+1w", ISO weeks like "2024-W05" (their monday), ISO dates, dotted dates
+// like "28.03.2024" and the passed layouts, e.g. "01/02/2006".
+func Date(value string, now time.Time, layouts ...string) (time.Time, error) {
+	input := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	today := midnight(now)
+
+	switch input {
+	case "today", "now":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if d, ok := weekday(input, today); ok {
+		return d, nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(input); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, invalidDate(value)
+		}
+		if m[2] == "w" {
+			n *= 7
+		}
+		return today.AddDate(0, 0, n), nil
+	}
+
+	if m := isoWeek.FindStringSubmatch(input); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		if d, ok := weekStart(year, week, now.Location()); ok {
+			return d, nil
+		}
+		return time.Time{}, fmt.Errorf("invalid week '%s' - %d has no week %d", value, year, week)
+	}
+
+	for _, layout := range append([]string{"2006-01-02", "02.01.2006"}, layouts...) {
+		if d, err := time.ParseInLocation(layout, strings.TrimSpace(value), now.Location()); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, invalidDate(value)
+}
+
+// Clock parses a time of day and places it on the calendar day of day in the location of now. Supported are
+// "hh:mm", "now" and offsets like "now-15m", "+1h" or "-1h30m" which are relative to the clock of now. An offset
+// which crosses midnight moves to the neighbouring day.
+func Clock(value string, day time.Time, now time.Time) (time.Time, error) {
+	input := strings.ToLower(strings.Join(strings.Fields(value), ""))
+
+	var t time.Time
+	var offset time.Duration
+	switch {
+	case input == "now":
+		t = now
+	case strings.HasPrefix(input, "now+") || strings.HasPrefix(input, "now-"),
+		strings.HasPrefix(input, "+") || strings.HasPrefix(input, "-"):
+		var err error
+		if offset, err = time.ParseDuration(strings.TrimPrefix(input, "now")); err != nil {
+			return time.Time{}, invalidClock(value)
+		}
+		t = now
+	default:
+		parsed, err := time.Parse("15:04", input)
+		if err != nil {
+			return time.Time{}, invalidClock(value)
+		}
+		t = parsed
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), now.Location()).Add(offset), nil
+}
+
+// EndAfter moves an end, which lies before the start, to the following day. This allows night shifts like
+// 22:00-06:00.
+func EndAfter(start time.Time, end time.Time) time.Time {
+	if end.Before(start) {
+		return end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// ===================
+// ===== PRIVATE =====
+// ===================
+
+// midnight returns the start of the day of t
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// weekday resolves "mon", "last mon" and "next mon". A plain weekday means the latest one including today.
+func weekday(input string, today time.Time) (time.Time, bool) {
+	words := strings.Fields(input)
+	prefix := ""
+	if len(words) == 2 {
+		prefix, words = words[0], words[1:]
+	}
+	if len(words) != 1 {
+		return time.Time{}, false
+	}
+	wd, ok := weekdays[words[0]]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	back := (int(today.Weekday()) - int(wd) + 7) % 7
+	switch prefix {
+	case "":
+		return today.AddDate(0, 0, -back), true
+	case "last":
+		if back == 0 {
+			back = 7
+		}
+		return today.AddDate(0, 0, -back), true
+	case "next":
+		ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), true
+	}
+	return time.Time{}, false
+}
+
+// weekStart returns the monday of the ISO week of the year
+func weekStart(year, week int, loc *time.Location) (time.Time, bool) {
+	// The 4th of january always belongs to the first week
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, false
+	}
+	return monday, true
+}
+
+func invalidDate(value string) error {
+	return fmt.Errorf("invalid date '%s' - use e.g. 2024-03-28, today, yesterday, mon, last friday, -2d or 2024-W05", value)
+}
+
+func invalidClock(value string) error {
+	return fmt.Errorf("invalid time '%s' - use e.g. 08:00, now, now-15m or +1h", value)
+}
@@ -0,0 +1,148 @@
+package when
+
+import (
+	"testing"
+	"time"
+)
+
+// now is a wednesday afternoon
+var now = time.Date(2024, 3, 27, 14, 30, 15, 0, time.FixedZone("CET", 3600))
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, now.Location())
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"today", day(2024, 3, 27)},
+		{"now", day(2024, 3, 27)},
+		{"Yesterday", day(2024, 3, 26)},
+		{"tomorrow", day(2024, 3, 28)},
+		{"wed", day(2024, 3, 27)},
+		{"mon", day(2024, 3, 25)},
+		{"Thursday", day(2024, 3, 21)},
+		{"sun", day(2024, 3, 24)},
+		{"last friday", day(2024, 3, 22)},
+		{"last  wed", day(2024, 3, 20)},
+		{"next mon", day(2024, 4, 1)},
+		{"next wednesday", day(2024, 4, 3)},
+		{"-2d", day(2024, 3, 25)},
+		{"+1d", day(2024, 3, 28)},
+		{"-1w", day(2024, 3, 20)},
+		{"-30d", day(2024, 2, 26)},
+		{"2024-W05", day(2024, 1, 29)},
+		{"2024-w1", day(2024, 1, 1)},
+		{"2020W53", day(2020, 12, 28)},
+		{"2025-W01", day(2024, 12, 30)},
+		{"2024-03-28", day(2024, 3, 28)},
+		{"28.03.2024", day(2024, 3, 28)},
+		{" 2019-02-28 ", day(2019, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		got, err := Date(tt.value, now)
+		if err != nil {
+			t.Errorf("Date(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != now.Location() {
+			t.Errorf("Date(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDate_Layouts(t *testing.T) {
+	for layout, value := range map[string]string{"02/01/2006": "28/03/2024", "01/02/2006": "03/28/2024"} {
+		for _, v := range []string{value, "28.03.2024"} {
+			if got, err := Date(v, now, layout); err != nil || !got.Equal(day(2024, 3, 28)) {
+				t.Errorf("Date(%q, %q) = %v, want %v (%v)", v, layout, got, day(2024, 3, 28), err)
+			}
+		}
+	}
+}
+
+func TestDate_Invalid(t *testing.T) {
+	for _, value := range []string{"", "someday", "last", "last week", "2d", "-2h", "2024-W54", "2021-W53", "2024-13-01", "28/03/2024", "12:00"} {
+		if got, err := Date(value, now); err == nil {
+			t.Errorf("Date(%q) = %v, want error", value, got)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	at := func(hour, min, sec int) time.Time {
+		return time.Date(2024, 3, 20, hour, min, sec, 0, now.Location())
+	}
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"08:00", at(8, 0, 0)},
+		{"8:05", at(8, 5, 0)},
+		{"23:59", at(23, 59, 0)},
+		{"now", at(14, 30, 15)},
+		{"NOW", at(14, 30, 15)},
+		{"now-15m", at(14, 15, 15)},
+		{"now + 1h", at(15, 30, 15)},
+		{"+1h", at(15, 30, 15)},
+		{"-1h30m", at(13, 0, 15)},
+	}
+
+	for _, tt := range tests {
+		got, err := Clock(tt.value, day(2024, 3, 20), now)
+		if err != nil {
+			t.Errorf("Clock(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Clock(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestClock_AcrossMidnight(t *testing.T) {
+	justAfter := time.Date(2024, 3, 28, 0, 5, 0, 0, now.Location())
+	if got, err := Clock("now-15m", midnight(justAfter), justAfter); err != nil || !got.Equal(justAfter.Add(-15*time.Minute)) {
+		t.Errorf("Clock(%q) = %v, want %v (%v)", "now-15m", got, justAfter.Add(-15*time.Minute), err)
+	}
+
+	justBefore := time.Date(2024, 3, 27, 23, 55, 0, 0, now.Location())
+	if got, err := Clock("+10m", midnight(justBefore), justBefore); err != nil || !got.Equal(justBefore.Add(10*time.Minute)) {
+		t.Errorf("Clock(%q) = %v, want %v (%v)", "+10m", got, justBefore.Add(10*time.Minute), err)
+	}
+}
+
+func TestClock_InLocationOfNow(t *testing.T) {
+	// Stored dates are midnight in UTC while the clock is read in the current zone
+	day := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	got, err := Clock("08:00", day, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 3, 20, 8, 0, 0, 0, now.Location()); !got.Equal(want) || got.Location() != now.Location() {
+		t.Fatalf("Clock(%q) = %v, want %v", "08:00", got, want)
+	}
+}
+
+func TestClock_Invalid(t *testing.T) {
+	for _, value := range []string{"", "24:00", "8", "noon", "now-", "now*2", "+1", "-2d", "today"} {
+		if got, err := Clock(value, day(2024, 3, 20), now); err == nil {
+			t.Errorf("Clock(%q) = %v, want error", value, got)
+		}
+	}
+}
+
+func TestEndAfter(t *testing.T) {
+	start := time.Date(2024, 3, 20, 22, 0, 0, 0, now.Location())
+	if got := EndAfter(start, start.Add(-16*time.Hour)); !got.Equal(start.Add(8 * time.Hour)) {
+		t.Errorf("EndAfter moved the end of a night shift to %v", got)
+	}
+	if got := EndAfter(start, start.Add(time.Hour)); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("EndAfter moved an end after the start to %v", got)
+	}
+}